@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"ozon-tesk-task/internal/app"
 	"ozon-tesk-task/internal/config"
 	"ozon-tesk-task/pkg/logger"
@@ -25,5 +26,26 @@ func main() {
 		mainLogger.Fatal(ctx, err.Error())
 	}
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			if err := app.Migrate(ctx, cfg, os.Args[2:]); err != nil {
+				mainLogger.Fatal(ctx, err.Error())
+			}
+		case "repair-activity":
+			if err := app.RepairPostActivity(ctx, cfg); err != nil {
+				mainLogger.Fatal(ctx, err.Error())
+			}
+		case "validate-manifest":
+			if err := app.ValidateManifest(ctx, cfg, os.Args[2:]); err != nil {
+				mainLogger.Fatal(ctx, err.Error())
+			}
+		default:
+			mainLogger.Fatal(ctx, fmt.Sprintf("unknown command %q", os.Args[1]))
+		}
+
+		return
+	}
+
 	app.Run(ctx, cfg)
 }
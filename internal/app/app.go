@@ -2,14 +2,18 @@ package app
 
 import (
 	"context"
+	nethttp "net/http"
 	"os"
 	"os/signal"
 	"ozon-tesk-task/internal/config"
 	"ozon-tesk-task/internal/database"
+	"ozon-tesk-task/internal/pubsub"
 	"ozon-tesk-task/internal/repository"
 	"ozon-tesk-task/internal/server"
 	"ozon-tesk-task/internal/service"
+	"ozon-tesk-task/internal/transport/grpc"
 	"ozon-tesk-task/internal/transport/http"
+	"ozon-tesk-task/internal/transport/rest"
 	"ozon-tesk-task/pkg/logger"
 	"syscall"
 
@@ -22,20 +26,59 @@ func Run(ctx context.Context, cfg *config.Config) {
 
 	mainLogger.Debug(ctx, "Storage picked", zap.String("type", cfg.StorageType))
 
-	db, err := database.NewDatabase(ctx, cfg)
-	if err != nil {
-		mainLogger.Fatal(ctx, err.Error())
+	var repo service.Repository
+
+	var db *database.Database
+
+	if cfg.StorageType == "memory" {
+		repo = repository.NewMemory()
+	} else {
+		var err error
+
+		db, err = database.NewDatabase(ctx, cfg)
+		if err != nil {
+			mainLogger.Fatal(ctx, err.Error())
+		}
+
+		if cfg.AutoMigrate {
+			err = db.MigrateUp(ctx)
+			if err == database.MigrationNoChange {
+				mainLogger.Info(ctx, "No change after migration")
+			} else if err != nil {
+				mainLogger.Fatal(ctx, err.Error())
+			} else {
+				mainLogger.Info(ctx, "Migration completed succesfully")
+			}
+		}
+
+		go db.LogStats(ctx)
+		go db.MonitorReplicas(ctx)
+
+		repo = repository.New(db)
 	}
 
-	repo := repository.New(db)
+	service := service.New(repo, cfg)
 
-	service := service.New(repo)
+	go service.PurgeIdempotencyKeys(ctx)
+
+	ps := pubsub.New(service.GetCommentsSince)
 
 	e := echo.New()
 
-	http.NewHandler(e, service, mainLogger)
+	if err := http.NewHandler(e, cfg, service, ps, mainLogger); err != nil {
+		mainLogger.Fatal(ctx, err.Error())
+	}
+
+	if err := rest.NewHandler(e, cfg, service, ps, mainLogger); err != nil {
+		mainLogger.Fatal(ctx, err.Error())
+	}
+
+	handler := e.Server.Handler
+	if db != nil {
+		handler = withDatabaseSession(handler)
+	}
 
-	srv := server.NewServer(cfg, e.Server.Handler)
+	srv := server.NewServer(cfg, handler)
 
 	go func() {
 		if err := srv.Run(ctx); err != nil {
@@ -43,6 +86,24 @@ func Run(ctx context.Context, cfg *config.Config) {
 		}
 	}()
 
+	session := func(ctx context.Context) context.Context { return ctx }
+	if db != nil {
+		session = database.WithSession
+	}
+
+	grpcHandler, err := grpc.NewServer(cfg, service, ps, mainLogger, grpc.WithContext(session)...)
+	if err != nil {
+		mainLogger.Fatal(ctx, err.Error())
+	}
+
+	grpcSrv := server.NewGRPCServer(cfg, grpcHandler)
+
+	go func() {
+		if err := grpcSrv.Run(ctx); err != nil {
+			mainLogger.Fatal(ctx, "failed to run gRPC server", zap.String("err", err.Error()))
+		}
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
@@ -53,4 +114,13 @@ func Run(ctx context.Context, cfg *config.Config) {
 	if err := srv.Stop(); err != nil {
 		mainLogger.Error(ctx, "failed to stop server", zap.String("err", err.Error()))
 	}
+
+	grpcSrv.Stop()
+}
+
+// withDatabaseSession makes every request read its own writes when reads go to replicas.
+func withDatabaseSession(next nethttp.Handler) nethttp.Handler {
+	return nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		next.ServeHTTP(w, r.WithContext(database.WithSession(r.Context())))
+	})
 }
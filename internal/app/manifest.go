@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"ozon-tesk-task/internal/config"
+	"ozon-tesk-task/internal/persisted"
+	"ozon-tesk-task/internal/transport/graph"
+	"ozon-tesk-task/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const validateManifestUsage = "usage: validate-manifest [path], GRAPHQL_PERSISTED_QUERY_MANIFEST by default"
+
+// ValidateManifest checks that every operation of a persisted query manifest is valid against the schema,
+// e.g. before a client build is released.
+func ValidateManifest(ctx context.Context, cfg *config.Config, args []string) error {
+	path := cfg.PersistedQueryManifest
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path == "" {
+		return errors.New(validateManifestUsage)
+	}
+
+	manifest, err := persisted.LoadManifest(path)
+	if err != nil {
+		return err
+	}
+
+	if err := manifest.Validate(graph.NewExecutableSchema(graph.Config{}).Schema()); err != nil {
+		return err
+	}
+
+	logger.GetLoggerFromCtx(ctx).Info(ctx, "Persisted query manifest is valid", zap.String("path", path), zap.Int("operations", len(manifest.Operations)))
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ozon-tesk-task/internal/config"
+	"ozon-tesk-task/internal/database"
+	"ozon-tesk-task/pkg/logger"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+const migrateUsage = "usage: migrate up | down [N] | goto N | version | force N"
+
+func Migrate(ctx context.Context, cfg *config.Config, args []string) error {
+	l := logger.GetLoggerFromCtx(ctx)
+
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	db, err := database.NewDatabase(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		err = db.MigrateUp(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+
+		err = db.MigrateDown(ctx, steps)
+	case "goto":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+
+		version, parseErr := strconv.ParseUint(args[1], 10, 32)
+		if parseErr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+
+		err = db.MigrateTo(ctx, uint(version))
+	case "force":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+
+		version, parseErr := strconv.Atoi(args[1])
+		if parseErr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+
+		err = db.ForceVersion(ctx, version)
+	case "version":
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	if err == database.MigrationNoChange {
+		l.Info(ctx, "No change after migration")
+	} else if err != nil {
+		return err
+	}
+
+	version, dirty, err := db.MigrationVersion(ctx)
+	if err == database.ErrNilVersion {
+		l.Info(ctx, "No migrations applied")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	l.Info(ctx, "Migration version", zap.Uint("version", version), zap.Bool("dirty", dirty))
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"ozon-tesk-task/internal/config"
+	"ozon-tesk-task/internal/database"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// RepairPostActivity recomputes the comment counters and last activity of all posts.
+func RepairPostActivity(ctx context.Context, cfg *config.Config) error {
+	if cfg.StorageType == "memory" {
+		return errors.New("in-memory storage has nothing to repair")
+	}
+
+	db, err := database.NewDatabase(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	fixed, err := repository.New(db).RepairPostActivity(ctx)
+	if err != nil {
+		return err
+	}
+
+	logger.GetLoggerFromCtx(ctx).Info(ctx, "Post activity repaired", zap.Int64("fixed posts", fixed))
+
+	return nil
+}
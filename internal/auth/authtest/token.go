@@ -0,0 +1,31 @@
+// Package authtest issues the bearer tokens that auth.Verifier accepts, for the tests of the transports.
+package authtest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Sign issues an HS256 token for the user that expires after the ttl, a zero ttl issues a token that never expires.
+func Sign(secret string, userId int32, ttl time.Duration) string {
+	claims := map[string]any{"sub": strconv.Itoa(int(userId))}
+	if ttl != 0 {
+		claims["exp"] = time.Now().Add(ttl).Unix()
+	}
+
+	unsigned := encodeSegment(map[string]any{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(claims)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func encodeSegment(value any) string {
+	data, _ := json.Marshal(value)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
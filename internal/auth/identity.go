@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+)
+
+type (
+	userKey       struct{}
+	connectionKey struct{}
+	clientKey     struct{}
+)
+
+// WithUser marks the request as sent by the authenticated user.
+func WithUser(ctx context.Context, userId int32) context.Context {
+	return context.WithValue(ctx, userKey{}, userId)
+}
+
+// User returns the authenticated user of the request.
+func User(ctx context.Context) (int32, bool) {
+	userId, ok := ctx.Value(userKey{}).(int32)
+	return userId, ok
+}
+
+// WithConnection records the address of the connection that the request came over.
+func WithConnection(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, connectionKey{}, addr)
+}
+
+// WithClient records the address of the client and the id derived from its user agent. Unlike the connection
+// they stay the same when the client retries a request over a new connection.
+func WithClient(ctx context.Context, addr string, agentId int32) context.Context {
+	return context.WithValue(ctx, clientKey{}, addr+"/"+strconv.Itoa(int(agentId)))
+}
+
+// Scope identifies the client of the request for the state kept per connected client, e.g. subscription limits.
+// It is the authenticated user or else the connection, as anonymous clients can only be told apart by their
+// connection: many of them share a user agent or an address.
+func Scope(ctx context.Context) string {
+	if userId, ok := User(ctx); ok {
+		return "user:" + strconv.Itoa(int(userId))
+	}
+
+	if addr, ok := ctx.Value(connectionKey{}).(string); ok {
+		return "connection:" + addr
+	}
+
+	return ""
+}
+
+// ClientScope identifies the client of the request for the state that has to outlive a connection, e.g.
+// idempotency keys, which are sent again by retries over new connections. It is the authenticated user or
+// else the address and user agent of the client.
+func ClientScope(ctx context.Context) string {
+	if userId, ok := User(ctx); ok {
+		return "user:" + strconv.Itoa(int(userId))
+	}
+
+	if client, ok := ctx.Value(clientKey{}).(string); ok {
+		return "client:" + client
+	}
+
+	return ""
+}
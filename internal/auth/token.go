@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	algorithm    = "HS256"
+	bearerPrefix = "Bearer "
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+}
+
+type claims struct {
+	Sub string `json:"sub"`
+	Exp int64  `json:"exp,omitempty"`
+}
+
+// Verifier checks the HS256 signed JWTs that clients send as bearer tokens.
+// The subject of a token is the id of the user.
+type Verifier struct {
+	secret []byte
+	now    func() time.Time
+}
+
+// NewVerifier creates a verifier for the tokens signed with the secret. Without a secret every token is invalid.
+func NewVerifier(secret string) *Verifier {
+	return &Verifier{
+		secret: []byte(secret),
+		now:    time.Now,
+	}
+}
+
+// Verify returns the user id of a valid token that has not expired. The "Bearer " prefix is optional.
+func (v *Verifier) Verify(token string) (int32, error) {
+	if len(v.secret) == 0 {
+		return 0, ErrInvalidToken
+	}
+
+	parts := strings.Split(strings.TrimPrefix(token, bearerPrefix), ".")
+	if len(parts) != 3 {
+		return 0, ErrInvalidToken
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil || h.Alg != algorithm {
+		return 0, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, sign(v.secret, parts[0]+"."+parts[1])) {
+		return 0, ErrInvalidToken
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	if c.Exp != 0 && !v.now().Before(time.Unix(c.Exp, 0)) {
+		return 0, ErrInvalidToken
+	}
+
+	userId, err := strconv.ParseInt(c.Sub, 10, 32)
+	if err != nil || userId <= 0 {
+		return 0, ErrInvalidToken
+	}
+
+	return int32(userId), nil
+}
+
+func sign(secret []byte, unsigned string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+
+	return mac.Sum(nil)
+}
+
+func decodeSegment(segment string, value any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, value)
+}
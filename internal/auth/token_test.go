@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"encoding/base64"
+	"ozon-tesk-task/internal/auth/authtest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "secret"
+
+func TestVerifier_Verify(t *testing.T) {
+	valid := authtest.Sign(testSecret, 42, time.Hour)
+
+	tests := []struct {
+		name    string
+		secret  string
+		token   string
+		want    int32
+		wantErr bool
+	}{
+		{
+			name:   "Valid token",
+			secret: testSecret,
+			token:  valid,
+			want:   42,
+		},
+		{
+			name:   "Bearer token without expiry",
+			secret: testSecret,
+			token:  "Bearer " + authtest.Sign(testSecret, 7, 0),
+			want:   7,
+		},
+		{
+			name:    "Expired token",
+			secret:  testSecret,
+			token:   authtest.Sign(testSecret, 42, -time.Minute),
+			wantErr: true,
+		},
+		{
+			name:    "Signed with another secret",
+			secret:  testSecret,
+			token:   authtest.Sign("other", 42, time.Hour),
+			wantErr: true,
+		},
+		{
+			name:    "Tampered subject",
+			secret:  testSecret,
+			token:   tamper(valid, `{"sub":"1"}`),
+			wantErr: true,
+		},
+		{
+			name:    "Unsigned token",
+			secret:  testSecret,
+			token:   base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + strings.Split(valid, ".")[1] + ".",
+			wantErr: true,
+		},
+		{
+			name:    "Not a token",
+			secret:  testSecret,
+			token:   "my-user",
+			wantErr: true,
+		},
+		{
+			name:    "No secret configured",
+			token:   authtest.Sign("", 42, time.Hour),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewVerifier(tt.secret).Verify(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verifier.Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Verifier.Verify() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// tamper replaces the claims of the token and keeps its signature.
+func tamper(token, claims string) string {
+	parts := strings.Split(token, ".")
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(claims))
+
+	return strings.Join(parts, ".")
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -13,11 +14,104 @@ type PostgresConfig struct {
 	Host     string `env:"POSTGRES_HOST"`
 	Port     string `env:"POSTGRES_PORT"`
 	DbName   string `env:"POSTGRES_DB"`
+	// ReplicaURLs is a comma separated list of connection URLs of read replicas
+	ReplicaURLs          string        `env:"POSTGRES_REPLICA_URLS"`
+	ReplicaCheckInterval time.Duration `env:"POSTGRES_REPLICA_CHECK_INTERVAL" env-default:"5s"`
+}
+
+type DatabaseConfig struct {
+	MaxOpenConns     int           `env:"DB_MAX_OPEN_CONNS" env-default:"25"`
+	MaxIdleConns     int           `env:"DB_MAX_IDLE_CONNS" env-default:"25"`
+	ConnMaxLifetime  time.Duration `env:"DB_CONN_MAX_LIFETIME" env-default:"30m"`
+	ConnMaxIdleTime  time.Duration `env:"DB_CONN_MAX_IDLE_TIME" env-default:"5m"`
+	StatementTimeout time.Duration `env:"DB_STATEMENT_TIMEOUT" env-default:"5s"`
+	StatsInterval    time.Duration `env:"DB_STATS_INTERVAL" env-default:"1m"`
+}
+
+type SqliteConfig struct {
+	Path        string        `env:"SQLITE_PATH" env-default:"data/posts.db"`
+	BusyTimeout time.Duration `env:"SQLITE_BUSY_TIMEOUT" env-default:"5s"`
+}
+
+type TransportConfig struct {
+	SSEKeepAliveInterval time.Duration `env:"SSE_KEEPALIVE_INTERVAL" env-default:"15s"`
+	// TrustedProxies is a comma separated list of addresses or CIDRs of the proxies whose
+	// X-Forwarded-For and X-Real-IP headers are trusted to tell the client address
+	TrustedProxies string `env:"TRUSTED_PROXIES"`
+}
+
+type AuthConfig struct {
+	// TokenSecret verifies the HS256 signed bearer tokens, without it every token is rejected
+	TokenSecret string `env:"AUTH_TOKEN_SECRET"`
+}
+
+type GraphQLConfig struct {
+	MaxQueryComplexity int `env:"GRAPHQL_MAX_COMPLEXITY" env-default:"5000"`
+	MaxQueryDepth      int `env:"GRAPHQL_MAX_DEPTH" env-default:"15"`
+	// ListComplexityWeight is the expected size of lists without a limit argument, e.g. comment replies
+	ListComplexityWeight int `env:"GRAPHQL_LIST_COMPLEXITY_WEIGHT" env-default:"5"`
+	// PersistedQueryManifest is the path of a persisted query manifest, if set only its operations are executed
+	PersistedQueryManifest string `env:"GRAPHQL_PERSISTED_QUERY_MANIFEST"`
+}
+
+type WebsocketConfig struct {
+	KeepAliveInterval       time.Duration `env:"WS_KEEPALIVE_INTERVAL" env-default:"10s"`
+	PingPongInterval        time.Duration `env:"WS_PING_PONG_INTERVAL" env-default:"10s"`
+	InitTimeout             time.Duration `env:"WS_INIT_TIMEOUT" env-default:"10s"`
+	AllowedOrigins          string        `env:"WS_ALLOWED_ORIGINS"`
+	RequireAuth             bool          `env:"WS_REQUIRE_AUTH" env-default:"false"`
+	MaxConnectionsPerIP     int           `env:"WS_MAX_CONNECTIONS_PER_IP" env-default:"10"`
+	MaxSubscriptionsPerUser int           `env:"WS_MAX_SUBSCRIPTIONS_PER_USER" env-default:"20"`
+}
+
+const (
+	// DepthPolicyFlatten attaches too deep replies to the deepest allowed ancestor and quotes the original parent.
+	DepthPolicyFlatten = "flatten"
+	// DepthPolicyReject rejects too deep replies.
+	DepthPolicyReject = "reject"
+)
+
+type CommentsConfig struct {
+	MaxCommentDepth    int    `env:"COMMENT_MAX_DEPTH" env-default:"10"`
+	CommentDepthPolicy string `env:"COMMENT_DEPTH_POLICY" env-default:"flatten"`
+}
+
+// ValidationConfig lowers the maximum lengths declared by the @constraint directives of the schema.
+type ValidationConfig struct {
+	PostTitleMaxLength   int `env:"POST_TITLE_MAX_LENGTH" env-default:"100"`
+	PostContentMaxLength int `env:"POST_CONTENT_MAX_LENGTH" env-default:"2000"`
+	CommentMaxLength     int `env:"COMMENT_MAX_LENGTH" env-default:"2000"`
+}
+
+type IdempotencyConfig struct {
+	// IdempotencyKeyTTL is how long the result of a mutation is replayed for its idempotency key, 0 disables the keys
+	IdempotencyKeyTTL        time.Duration `env:"IDEMPOTENCY_KEY_TTL" env-default:"24h"`
+	IdempotencyPurgeInterval time.Duration `env:"IDEMPOTENCY_PURGE_INTERVAL" env-default:"1h"`
+}
+
+type BatchConfig struct {
+	MaxBatchSize int `env:"BATCH_MAX_SIZE" env-default:"100"`
+}
+
+type GRPCConfig struct {
+	GRPCPort string `env:"GRPC_PORT" env-default:"9090"`
 }
 
 type Config struct {
 	PostgresConfig
+	SqliteConfig
+	DatabaseConfig
+	TransportConfig
+	AuthConfig
+	GraphQLConfig
+	WebsocketConfig
+	CommentsConfig
+	ValidationConfig
+	IdempotencyConfig
+	BatchConfig
+	GRPCConfig
 	MigrationsPath string `env:"MIGRATIONS_PATH"`
+	AutoMigrate    bool   `env:"AUTO_MIGRATE" env-default:"false"`
 	StorageType    string `env:"STORAGE_TYPE"`
 
 	ServicePort string `env:"SERVICE_PORT"`
@@ -36,5 +130,13 @@ func New() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
+	if cfg.CommentDepthPolicy != DepthPolicyFlatten && cfg.CommentDepthPolicy != DepthPolicyReject {
+		return nil, fmt.Errorf("unknown comment depth policy %q", cfg.CommentDepthPolicy)
+	}
+
+	if cfg.RequireAuth && cfg.TokenSecret == "" {
+		return nil, errors.New("WS_REQUIRE_AUTH needs AUTH_TOKEN_SECRET to verify the tokens")
+	}
+
 	return &cfg, nil
 }
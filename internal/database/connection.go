@@ -4,8 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
+	"os"
 	"ozon-tesk-task/internal/config"
 	"ozon-tesk-task/pkg/logger"
+	"path/filepath"
+	"strings"
 
 	"go.uber.org/zap"
 )
@@ -22,15 +26,21 @@ func NewDatabase(ctx context.Context, cfg *config.Config) (*Database, error) {
 
 	switch cfg.StorageType {
 	case "postgres":
-		db = New(cfg, "postgres")
 		dsn = fmt.Sprintf("user=%s password=%s dbname=%s sslmode=disable host=%s port=%s", cfg.UserName, cfg.Password, cfg.DbName, cfg.Host, cfg.Port)
 		dbURL = fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", cfg.UserName, cfg.Password, cfg.Host, cfg.Port, cfg.DbName)
+		db = New(cfg, "postgres", dbURL)
 
 		l.Debug(ctx, "Connecting to postgres database", zap.String("dsn", dsn), zap.String("dbURL", dbURL))
-	case "memory":
-		db = New(cfg, "sqlite")
-		dsn = "file::memory:?cache=shared"
-		dbURL = "sqlite://file::memory:?cache=shared"
+	case "sqlite":
+		if err = os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create sqlite directory: %w", err)
+		}
+
+		pragmas := fmt.Sprintf("_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate&_time_format=sqlite", cfg.BusyTimeout.Milliseconds())
+
+		dsn = fmt.Sprintf("file:%s?%s", cfg.Path, pragmas)
+		dbURL = fmt.Sprintf("sqlite://%s?%s", cfg.Path, pragmas)
+		db = New(cfg, "sqlite", dbURL)
 
 		l.Debug(ctx, "Connecting to sqlite database", zap.String("dsn", dsn), zap.String("dbURL", dbURL))
 	default:
@@ -42,14 +52,29 @@ func NewDatabase(ctx context.Context, cfg *config.Config) (*Database, error) {
 		return nil, err
 	}
 
-	err = db.MigrateUp(ctx, dbURL)
-	if err == MigrationNoChange {
-		l.Info(ctx, "No change after migration")
-	} else if err != nil {
-		return nil, err
-	}
+	db.logs = l
+
+	if cfg.StorageType == "postgres" && cfg.ReplicaURLs != "" {
+		for i, replicaURL := range strings.Split(cfg.ReplicaURLs, ",") {
+			replicaURL = strings.TrimSpace(replicaURL)
+			name := replicaName(replicaURL, i)
 
-	l.Info(ctx, "Migration completed succesfully")
+			l.Debug(ctx, "Connecting to postgres replica", zap.String("replica", name))
+
+			if err = db.ConnectReplica(ctx, name, replicaURL); err != nil {
+				return nil, fmt.Errorf("failed to open replica %s: %w", name, err)
+			}
+		}
+	}
 
 	return db, nil
 }
+
+// replicaName identifies the replica in logs without its credentials.
+func replicaName(replicaURL string, i int) string {
+	if u, err := url.Parse(replicaURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+
+	return fmt.Sprintf("replica %d", i+1)
+}
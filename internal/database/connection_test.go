@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"ozon-tesk-task/internal/config"
+	"ozon-tesk-task/pkg/logger"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newSqliteDatabase(t *testing.T, ctx context.Context, path string, busyTimeout time.Duration) *Database {
+	t.Helper()
+
+	cfg := &config.Config{StorageType: "sqlite", SqliteConfig: config.SqliteConfig{Path: path, BusyTimeout: busyTimeout}}
+
+	db, err := NewDatabase(ctx, cfg)
+	if err != nil {
+		t.Fatalf("NewDatabase() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestNewDatabase_Sqlite(t *testing.T) {
+	log, _ := logger.New("test")
+	ctx := context.WithValue(context.Background(), logger.LoggerKey, log)
+
+	// the directory of the database file does not exist yet
+	path := filepath.Join(t.TempDir(), "data", "posts.db")
+	busyTimeout := 200 * time.Millisecond
+
+	db := newSqliteDatabase(t, ctx, path, busyTimeout)
+
+	t.Run("Pragmas", func(t *testing.T) {
+		var journalMode string
+		if err := db.DB.QueryRow(`PRAGMA journal_mode`).Scan(&journalMode); err != nil {
+			t.Fatalf("failed to read journal_mode: %v", err)
+		}
+		if journalMode != "wal" {
+			t.Errorf("journal_mode = %q, want %q", journalMode, "wal")
+		}
+
+		var foreignKeys int
+		if err := db.DB.QueryRow(`PRAGMA foreign_keys`).Scan(&foreignKeys); err != nil {
+			t.Fatalf("failed to read foreign_keys: %v", err)
+		}
+		if foreignKeys != 1 {
+			t.Errorf("foreign_keys = %d, want 1", foreignKeys)
+		}
+
+		var timeout int64
+		if err := db.DB.QueryRow(`PRAGMA busy_timeout`).Scan(&timeout); err != nil {
+			t.Fatalf("failed to read busy_timeout: %v", err)
+		}
+		if timeout != busyTimeout.Milliseconds() {
+			t.Errorf("busy_timeout = %d, want %d", timeout, busyTimeout.Milliseconds())
+		}
+	})
+
+	t.Run("Transactions take the write lock when they begin", func(t *testing.T) {
+		other := newSqliteDatabase(t, ctx, path, busyTimeout)
+
+		tx, err := db.DB.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("BeginTx() error = %v", err)
+		}
+		defer tx.Rollback()
+
+		// a deferred transaction would begin and fail only on its first write
+		start := time.Now()
+		otherTx, err := other.DB.BeginTx(ctx, nil)
+		if err == nil {
+			otherTx.Rollback()
+			t.Fatal("BeginTx() of a second writer succeeded, want the database to be locked")
+		}
+		if elapsed := time.Since(start); elapsed < busyTimeout {
+			t.Errorf("BeginTx() failed after %v, want to wait for the busy timeout %v", elapsed, busyTimeout)
+		}
+	})
+
+	t.Run("Data survives a reopen", func(t *testing.T) {
+		if err := db.MigrateUp(ctx); err != nil {
+			t.Fatalf("MigrateUp() error = %v", err)
+		}
+		if _, err := db.DB.Exec(`INSERT INTO posts (title, content, created_at, last_activity_at) VALUES ('title', 'content', $1, $1)`, time.Now().UTC()); err != nil {
+			t.Fatalf("failed to insert post: %v", err)
+		}
+		if err := db.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		reopened := newSqliteDatabase(t, ctx, path, busyTimeout)
+
+		var count int
+		if err := reopened.DB.QueryRow(`SELECT COUNT(*) FROM posts WHERE title = 'title'`).Scan(&count); err != nil {
+			t.Fatalf("failed to read posts: %v", err)
+		}
+		if count != 1 {
+			t.Errorf("posts after reopen = %d, want 1", count)
+		}
+	})
+}
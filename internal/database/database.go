@@ -5,66 +5,123 @@ import (
 	"database/sql"
 	"fmt"
 	"ozon-tesk-task/internal/config"
+	"ozon-tesk-task/pkg/logger"
+	"sync/atomic"
+	"time"
 
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
+	"go.uber.org/zap"
 	_ "modernc.org/sqlite"
 )
 
-var (
-	MigrationNoChange = migrate.ErrNoChange
-)
-
 type Database struct {
-	DB     *sql.DB
-	driver string
-	config *config.Config
+	DB       *sql.DB
+	replicas []*replica
+	next     atomic.Uint32
+	driver   string
+	url      string
+	config   *config.Config
+	logs     logger.Logger
 }
 
-func New(cfg *config.Config, driver string) *Database {
+func New(cfg *config.Config, driver, url string) *Database {
 	return &Database{
 		config: cfg,
 		driver: driver,
+		url:    url,
 	}
 }
 
 func (d *Database) Connect(ctx context.Context, dsn string) error {
+	db, err := d.open(ctx, dsn)
+	if err != nil {
+		return err
+	}
+
+	d.DB = db
+
+	return nil
+}
+
+func (d *Database) open(ctx context.Context, dsn string) (*sql.DB, error) {
 	db, err := sql.Open(d.driver, dsn)
 
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
+	d.configurePool(db)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	d.DB = db
+	return db, nil
+}
 
-	return nil
+func (d *Database) configurePool(db *sql.DB) {
+	db.SetMaxOpenConns(d.config.MaxOpenConns)
+	if d.config.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(d.config.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(d.config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(d.config.ConnMaxIdleTime)
 }
 
-func (d *Database) MigrateUp(ctx context.Context, dbURL string) error {
-	m, err := migrate.New(fmt.Sprintf("file://%s/%s", d.config.MigrationsPath, d.driver), dbURL)
-	if err != nil {
-		return fmt.Errorf("failed to create migration: %w", err)
+// WithStatementTimeout limits the context by the configured statement timeout.
+func (d *Database) WithStatementTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.config.StatementTimeout <= 0 {
+		return context.WithCancel(ctx)
 	}
 
-	err = m.Up()
-	if err == migrate.ErrNoChange {
-		return MigrationNoChange
+	return context.WithTimeout(ctx, d.config.StatementTimeout)
+}
+
+func (d *Database) LogStats(ctx context.Context) {
+	if d.config.StatsInterval <= 0 {
+		return
 	}
 
-	if err != nil {
-		return fmt.Errorf("failed to make migration up: %w", err)
+	l := logger.GetLoggerFromCtx(ctx)
+
+	ticker := time.NewTicker(d.config.StatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			logPoolStats(ctx, l, "primary", d.DB)
+
+			for _, r := range d.replicas {
+				logPoolStats(ctx, l, r.name, r.db)
+			}
+		}
 	}
+}
 
-	return nil
+func logPoolStats(ctx context.Context, l logger.Logger, name string, db *sql.DB) {
+	stats := db.Stats()
+
+	l.Info(ctx, "Database pool stats",
+		zap.String("database", name),
+		zap.Int("open", stats.OpenConnections),
+		zap.Int("in use", stats.InUse),
+		zap.Int("idle", stats.Idle),
+		zap.Int64("wait count", stats.WaitCount),
+		zap.Duration("wait duration", stats.WaitDuration),
+		zap.Int64("max idle closed", stats.MaxIdleClosed),
+		zap.Int64("max idle time closed", stats.MaxIdleTimeClosed),
+		zap.Int64("max lifetime closed", stats.MaxLifetimeClosed),
+	)
 }
 
 func (d *Database) Close() error {
+	for _, r := range d.replicas {
+		r.db.Close()
+	}
+
 	return d.DB.Close()
 }
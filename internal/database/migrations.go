@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+var (
+	MigrationNoChange = migrate.ErrNoChange
+	ErrNilVersion     = migrate.ErrNilVersion
+)
+
+//go:embed migrations
+var migrationsFS embed.FS
+
+// newMigration reads migrations embedded into the binary unless MIGRATIONS_PATH overrides them.
+func (d *Database) newMigration() (*migrate.Migrate, error) {
+	if d.config.MigrationsPath != "" {
+		m, err := migrate.New(fmt.Sprintf("file://%s/%s", d.config.MigrationsPath, d.driver), d.url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create migration: %w", err)
+		}
+
+		return m, nil
+	}
+
+	source, err := iofs.New(migrationsFS, path.Join("migrations", d.driver))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, d.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration: %w", err)
+	}
+
+	return m, nil
+}
+
+func (d *Database) MigrateUp(ctx context.Context) error {
+	m, err := d.newMigration()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	err = m.Up()
+	if err == migrate.ErrNoChange {
+		return MigrationNoChange
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to make migration up: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) MigrateDown(ctx context.Context, steps int) error {
+	m, err := d.newMigration()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	err = m.Steps(-steps)
+	if errors.Is(err, migrate.ErrNoChange) {
+		return MigrationNoChange
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to make migration down: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) MigrateTo(ctx context.Context, version uint) error {
+	m, err := d.newMigration()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	err = m.Migrate(version)
+	if err == migrate.ErrNoChange {
+		return MigrationNoChange
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to migrate to version %d: %w", version, err)
+	}
+
+	return nil
+}
+
+func (d *Database) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	m, err := d.newMigration()
+	if err != nil {
+		return 0, false, err
+	}
+	defer m.Close()
+
+	version, dirty, err := m.Version()
+	if err == migrate.ErrNilVersion {
+		return 0, false, ErrNilVersion
+	}
+
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get migration version: %w", err)
+	}
+
+	return version, dirty, nil
+}
+
+func (d *Database) ForceVersion(ctx context.Context, version int) error {
+	m, err := d.newMigration()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err = m.Force(version); err != nil {
+		return fmt.Errorf("failed to force version %d: %w", version, err)
+	}
+
+	return nil
+}
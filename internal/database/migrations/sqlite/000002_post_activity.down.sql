@@ -0,0 +1,2 @@
+ALTER TABLE posts DROP COLUMN last_activity_at;
+ALTER TABLE posts DROP COLUMN comment_count;
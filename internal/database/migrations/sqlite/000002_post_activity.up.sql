@@ -0,0 +1,6 @@
+ALTER TABLE posts ADD COLUMN comment_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN last_activity_at DATETIME;
+
+UPDATE posts SET
+  comment_count = (SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id),
+  last_activity_at = COALESCE((SELECT MAX(created_at) FROM comments WHERE comments.post_id = posts.id), created_at);
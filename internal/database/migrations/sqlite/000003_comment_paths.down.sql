@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_comments_path;
+
+ALTER TABLE comments DROP COLUMN depth;
+ALTER TABLE comments DROP COLUMN path;
@@ -0,0 +1,13 @@
+ALTER TABLE comments ADD COLUMN path TEXT NOT NULL DEFAULT '';
+ALTER TABLE comments ADD COLUMN depth INTEGER NOT NULL DEFAULT 0;
+
+-- path lists the ids from the thread root down to the comment, e.g. '1/4/9/'
+WITH RECURSIVE tree (id, path, depth) AS (
+  SELECT id, CAST(id AS TEXT) || '/', 0 FROM comments WHERE parent_comment_id IS NULL
+  UNION ALL
+  SELECT c.id, tree.path || CAST(c.id AS TEXT) || '/', tree.depth + 1
+  FROM comments c JOIN tree ON c.parent_comment_id = tree.id
+)
+UPDATE comments SET path = tree.path, depth = tree.depth FROM tree WHERE comments.id = tree.id;
+
+CREATE INDEX IF NOT EXISTS idx_comments_path ON comments (path);
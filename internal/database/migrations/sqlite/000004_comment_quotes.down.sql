@@ -0,0 +1 @@
+ALTER TABLE comments DROP COLUMN quoted_comment_id;
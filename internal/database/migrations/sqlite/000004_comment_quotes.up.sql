@@ -0,0 +1 @@
+ALTER TABLE comments ADD COLUMN quoted_comment_id INTEGER REFERENCES comments(id) ON DELETE SET NULL;
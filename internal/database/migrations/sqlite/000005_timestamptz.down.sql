@@ -0,0 +1,3 @@
+-- SQLite has no time zone aware type: timestamps are stored as UTC text.
+-- The UTC timestamps are read correctly by the previous versions as well, so they are kept.
+SELECT 1;
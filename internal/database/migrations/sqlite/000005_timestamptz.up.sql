@@ -0,0 +1,29 @@
+-- SQLite has no time zone aware type: timestamps are stored as UTC text.
+-- Rows written before were formatted by time.Time.String in the local time zone of the service,
+-- e.g. '2025-01-02 18:04:05.123 +0300 MSK', and sort wrong against UTC ones. They are converted to
+-- UTC in the format the driver writes now, '2025-01-02 15:04:05.123+00:00'. Rows without an offset
+-- come from CURRENT_TIMESTAMP, which is UTC already.
+UPDATE posts SET created_at = strftime('%Y-%m-%d %H:%M:%S',
+    substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)
+  ) || substr(created_at, 20, instr(substr(created_at, 20), ' ') - 1) || '+00:00'
+WHERE created_at LIKE '% +%' OR created_at LIKE '% -%';
+
+UPDATE posts SET updated_at = strftime('%Y-%m-%d %H:%M:%S',
+    substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)
+  ) || substr(updated_at, 20, instr(substr(updated_at, 20), ' ') - 1) || '+00:00'
+WHERE updated_at LIKE '% +%' OR updated_at LIKE '% -%';
+
+UPDATE posts SET last_activity_at = strftime('%Y-%m-%d %H:%M:%S',
+    substr(last_activity_at, 1, 19) || substr(last_activity_at, 20 + instr(substr(last_activity_at, 20), ' '), 3) || ':' || substr(last_activity_at, 23 + instr(substr(last_activity_at, 20), ' '), 2)
+  ) || substr(last_activity_at, 20, instr(substr(last_activity_at, 20), ' ') - 1) || '+00:00'
+WHERE last_activity_at LIKE '% +%' OR last_activity_at LIKE '% -%';
+
+UPDATE comments SET created_at = strftime('%Y-%m-%d %H:%M:%S',
+    substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)
+  ) || substr(created_at, 20, instr(substr(created_at, 20), ' ') - 1) || '+00:00'
+WHERE created_at LIKE '% +%' OR created_at LIKE '% -%';
+
+UPDATE comments SET updated_at = strftime('%Y-%m-%d %H:%M:%S',
+    substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)
+  ) || substr(updated_at, 20, instr(substr(updated_at, 20), ' ') - 1) || '+00:00'
+WHERE updated_at LIKE '% +%' OR updated_at LIKE '% -%';
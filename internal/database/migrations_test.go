@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"ozon-tesk-task/internal/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMigrateTo_SqliteTimestampsToUTC(t *testing.T) {
+	ctx := context.Background()
+
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	dsn := fmt.Sprintf("file:/%s?mode=memory&cache=shared&_time_format=sqlite", name)
+
+	db := New(&config.Config{}, "sqlite", "sqlite://"+dsn)
+	if err := db.Connect(ctx, dsn); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.MigrateTo(ctx, 4); err != nil {
+		t.Fatalf("MigrateTo(4) error = %v", err)
+	}
+
+	// the rows as the driver formatted them before the timestamps were set in UTC
+	rows := []string{
+		"2025-01-02 18:04:05.123456789 +0300 MSK m=+0.000012345",
+		"2025-01-01 21:30:00 -0500 EST",
+		"2025-01-02 15:04:05",
+	}
+	for i, createdAt := range rows {
+		if _, err := db.DB.Exec(`INSERT INTO posts (id, title, content, created_at, last_activity_at) VALUES ($1, 'title', 'content', $2, $2)`, i+1, createdAt); err != nil {
+			t.Fatalf("failed to insert post: %v", err)
+		}
+	}
+
+	if err := db.MigrateTo(ctx, 5); err != nil {
+		t.Fatalf("MigrateTo(5) error = %v", err)
+	}
+
+	want := []time.Time{
+		time.Date(2025, 1, 2, 15, 4, 5, 123456789, time.UTC),
+		time.Date(2025, 1, 2, 2, 30, 0, 0, time.UTC),
+		time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC),
+	}
+	for i, wantTime := range want {
+		var createdAt, lastActivityAt time.Time
+		if err := db.DB.QueryRow(`SELECT created_at, last_activity_at FROM posts WHERE id = $1`, i+1).Scan(&createdAt, &lastActivityAt); err != nil {
+			t.Fatalf("failed to read post: %v", err)
+		}
+
+		if !createdAt.Equal(wantTime) || !lastActivityAt.Equal(wantTime) {
+			t.Errorf("post %d timestamps = %v, %v, want %v", i+1, createdAt, lastActivityAt, wantTime)
+		}
+	}
+}
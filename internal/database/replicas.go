@@ -0,0 +1,205 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"ozon-tesk-task/pkg/logger"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type sessionKey struct{}
+
+type session struct {
+	wrote atomic.Bool
+}
+
+type replica struct {
+	db      *sql.DB
+	name    string
+	healthy atomic.Bool
+}
+
+// WithSession starts a read-your-writes session: once a write is made with the returned
+// context, the following reads made with it go to the primary.
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+// ConnectReplica adds a read replica. A replica that is unreachable at startup is
+// marked unhealthy and picked up by MonitorReplicas once it becomes available.
+func (d *Database) ConnectReplica(ctx context.Context, name, dsn string) error {
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return err
+	}
+
+	d.configurePool(db)
+
+	r := &replica{db: db, name: name}
+	r.healthy.Store(db.PingContext(ctx) == nil)
+
+	d.replicas = append(d.replicas, r)
+
+	return nil
+}
+
+// Runner runs queries, it is implemented by *sql.DB and by the readers of the replicas.
+type Runner interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Reader returns a healthy replica in round-robin order. It falls back to the primary if there is
+// no healthy replica or the session of the context has already written to the primary.
+// Queries that fail on the replica are retried on the primary.
+func (d *Database) Reader(ctx context.Context) Runner {
+	if r := d.replica(ctx); r != nil {
+		return &replicaReader{db: d, replica: r}
+	}
+
+	return d.DB
+}
+
+// BeginReadOnly starts a read-only transaction on a replica picked as by Reader,
+// or on the primary if there is none or the replica fails to start it.
+func (d *Database) BeginReadOnly(ctx context.Context, isolation sql.IsolationLevel) (*sql.Tx, error) {
+	opts := &sql.TxOptions{Isolation: isolation, ReadOnly: true}
+
+	if r := d.replica(ctx); r != nil {
+		tx, err := r.db.BeginTx(ctx, opts)
+		if err == nil || ctx.Err() != nil {
+			return tx, err
+		}
+
+		d.logRetry(ctx, r, err)
+	}
+
+	return d.DB.BeginTx(ctx, opts)
+}
+
+func (d *Database) replica(ctx context.Context) *replica {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok && s.wrote.Load() {
+		return nil
+	}
+
+	n := len(d.replicas)
+	start := int(d.next.Add(1))
+
+	for i := 0; i < n; i++ {
+		r := d.replicas[(start+i)%n]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// Writer returns the primary and marks the session of the context as written.
+func (d *Database) Writer(ctx context.Context) *sql.DB {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		s.wrote.Store(true)
+	}
+
+	return d.DB
+}
+
+func (d *Database) MonitorReplicas(ctx context.Context) {
+	if len(d.replicas) == 0 || d.config.ReplicaCheckInterval <= 0 {
+		return
+	}
+
+	l := logger.GetLoggerFromCtx(ctx)
+
+	ticker := time.NewTicker(d.config.ReplicaCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, r := range d.replicas {
+				pingCtx, cancel := context.WithTimeout(ctx, d.config.ReplicaCheckInterval)
+				err := r.db.PingContext(pingCtx)
+				cancel()
+
+				healthy := err == nil
+				if r.healthy.Swap(healthy) != healthy {
+					if healthy {
+						l.Info(ctx, "Replica is healthy again", zap.String("replica", r.name))
+					} else {
+						l.Warn(ctx, "Replica is unhealthy, reading from primary", zap.String("replica", r.name), zap.String("err", err.Error()))
+					}
+				}
+			}
+		}
+	}
+}
+
+// replicaReader runs queries on a replica and retries the failed ones on the primary,
+// so that reads do not fail until MonitorReplicas notices that the replica is down.
+type replicaReader struct {
+	db      *Database
+	replica *replica
+}
+
+func (r *replicaReader) Exec(query string, args ...any) (sql.Result, error) {
+	return r.ExecContext(context.Background(), query, args...)
+}
+
+func (r *replicaReader) Query(query string, args ...any) (*sql.Rows, error) {
+	return r.QueryContext(context.Background(), query, args...)
+}
+
+func (r *replicaReader) QueryRow(query string, args ...any) *sql.Row {
+	return r.QueryRowContext(context.Background(), query, args...)
+}
+
+func (r *replicaReader) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	result, err := r.replica.db.ExecContext(ctx, query, args...)
+	if err == nil || ctx.Err() != nil {
+		return result, err
+	}
+
+	r.db.logRetry(ctx, r.replica, err)
+
+	return r.db.DB.ExecContext(ctx, query, args...)
+}
+
+func (r *replicaReader) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := r.replica.db.QueryContext(ctx, query, args...)
+	if err == nil || ctx.Err() != nil {
+		return rows, err
+	}
+
+	r.db.logRetry(ctx, r.replica, err)
+
+	return r.db.DB.QueryContext(ctx, query, args...)
+}
+
+func (r *replicaReader) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	row := r.replica.db.QueryRowContext(ctx, query, args...)
+	if row.Err() == nil || ctx.Err() != nil {
+		return row
+	}
+
+	r.db.logRetry(ctx, r.replica, row.Err())
+
+	return r.db.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (d *Database) logRetry(ctx context.Context, r *replica, err error) {
+	if d.logs == nil {
+		return
+	}
+
+	d.logs.Warn(ctx, "Replica failed, retrying on primary", zap.String("replica", r.name), zap.String("err", err.Error()))
+}
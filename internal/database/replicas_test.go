@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"ozon-tesk-task/internal/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func newTestDatabase(t *testing.T, replicas ...string) *Database {
+	t.Helper()
+
+	ctx := context.Background()
+
+	db := New(&config.Config{}, "sqlite", "")
+	require.NoError(t, db.Connect(ctx, "file:/primary?mode=memory"))
+
+	for _, name := range replicas {
+		require.NoError(t, db.ConnectReplica(ctx, name, "file:/"+name+"?mode=memory"))
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// readerDB returns the database the reader runs its queries on.
+func readerDB(runner Runner) *sql.DB {
+	if r, ok := runner.(*replicaReader); ok {
+		return r.replica.db
+	}
+
+	return runner.(*sql.DB)
+}
+
+func TestReaderWithoutReplicas(t *testing.T) {
+	db := newTestDatabase(t)
+
+	assert.Same(t, db.DB, db.Reader(context.Background()))
+}
+
+func TestReaderRoundRobin(t *testing.T) {
+	db := newTestDatabase(t, "first", "second")
+
+	first := readerDB(db.Reader(context.Background()))
+	second := readerDB(db.Reader(context.Background()))
+
+	assert.NotSame(t, db.DB, first)
+	assert.NotSame(t, db.DB, second)
+	assert.NotSame(t, first, second)
+}
+
+func TestReaderSkipsUnhealthyReplica(t *testing.T) {
+	db := newTestDatabase(t, "first", "second")
+
+	db.replicas[0].healthy.Store(false)
+
+	for i := 0; i < 3; i++ {
+		assert.Same(t, db.replicas[1].db, readerDB(db.Reader(context.Background())))
+	}
+
+	db.replicas[1].healthy.Store(false)
+
+	assert.Same(t, db.DB, db.Reader(context.Background()))
+}
+
+func TestReaderAfterWriteInSession(t *testing.T) {
+	db := newTestDatabase(t, "replica")
+
+	ctx := WithSession(context.Background())
+
+	assert.Same(t, db.replicas[0].db, readerDB(db.Reader(ctx)))
+	assert.Same(t, db.DB, db.Writer(ctx))
+	assert.Same(t, db.DB, db.Reader(ctx))
+
+	assert.Same(t, db.replicas[0].db, readerDB(db.Reader(context.Background())))
+}
+
+func TestReaderRetriesOnPrimary(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t, "replica")
+
+	_, err := db.DB.ExecContext(ctx, "CREATE TABLE posts (id INTEGER)")
+	require.NoError(t, err)
+	_, err = db.DB.ExecContext(ctx, "INSERT INTO posts VALUES (1)")
+	require.NoError(t, err)
+
+	// The replica has no posts table, so every read fails on it.
+	var id int
+	require.NoError(t, db.Reader(ctx).QueryRowContext(ctx, "SELECT id FROM posts").Scan(&id))
+	assert.Equal(t, 1, id)
+
+	rows, err := db.Reader(ctx).QueryContext(ctx, "SELECT id FROM posts")
+	require.NoError(t, err)
+	assert.True(t, rows.Next())
+	require.NoError(t, rows.Close())
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = db.Reader(canceled).QueryContext(canceled, "SELECT id FROM posts")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBeginReadOnly(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t, "replica")
+
+	_, err := db.replicas[0].db.ExecContext(ctx, "CREATE TABLE replica (id INTEGER)")
+	require.NoError(t, err)
+
+	tx, err := db.BeginReadOnly(ctx, sql.LevelDefault)
+	require.NoError(t, err)
+
+	_, err = tx.ExecContext(ctx, "SELECT id FROM replica")
+	assert.NoError(t, err, "transaction is not started on the replica")
+	require.NoError(t, tx.Rollback())
+
+	// The session is not marked as written by a read-only transaction.
+	session := WithSession(ctx)
+	tx, err = db.BeginReadOnly(session, sql.LevelDefault)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+	assert.Same(t, db.replicas[0].db, readerDB(db.Reader(session)))
+
+	require.NoError(t, db.replicas[0].db.Close())
+
+	tx, err = db.BeginReadOnly(ctx, sql.LevelDefault)
+	require.NoError(t, err, "transaction is not retried on the primary")
+	require.NoError(t, tx.Rollback())
+}
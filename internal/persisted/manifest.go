@@ -0,0 +1,92 @@
+package persisted
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// ManifestFormat is the format of the manifests generated by the Apollo client tooling.
+const ManifestFormat = "apollo-persisted-query-manifest"
+
+// Manifest lists the operations that clients are allowed to execute, the id of an operation is
+// the hex encoded SHA-256 hash of its body, as sent in the automatic persisted queries extension.
+type Manifest struct {
+	Format     string      `json:"format"`
+	Version    int         `json:"version"`
+	Operations []Operation `json:"operations"`
+}
+
+type Operation struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Body string `json:"body"`
+}
+
+// LoadManifest reads the manifest and checks that every id matches the body.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if manifest.Format != ManifestFormat || manifest.Version != 1 {
+		return nil, fmt.Errorf("unsupported manifest format %q version %d", manifest.Format, manifest.Version)
+	}
+
+	ids := make(map[string]struct{}, len(manifest.Operations))
+	for _, op := range manifest.Operations {
+		if hash := Hash(op.Body); op.ID != hash {
+			return nil, fmt.Errorf("operation %s has id %q, but the hash of its body is %q", op.Name, op.ID, hash)
+		}
+
+		if _, ok := ids[op.ID]; ok {
+			return nil, fmt.Errorf("operation %s is listed twice", op.Name)
+		}
+		ids[op.ID] = struct{}{}
+	}
+
+	return &manifest, nil
+}
+
+// Validate checks every operation against the schema and returns all problems at once.
+func (m *Manifest) Validate(schema *ast.Schema) error {
+	var errs []error
+
+	for _, op := range m.Operations {
+		if _, list := gqlparser.LoadQuery(schema, op.Body); len(list) > 0 {
+			for _, err := range list {
+				errs = append(errs, fmt.Errorf("operation %s (%s): %w", op.Name, op.ID, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Queries returns the bodies of the operations by id.
+func (m *Manifest) Queries() map[string]string {
+	queries := make(map[string]string, len(m.Operations))
+	for _, op := range m.Operations {
+		queries[op.ID] = op.Body
+	}
+
+	return queries
+}
+
+func Hash(query string) string {
+	hash := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,117 @@
+package persisted
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const testSchema = `
+type Query {
+  posts: [Post]
+}
+
+type Post {
+  id: ID!
+  title: String!
+}
+`
+
+func operation(name, body string) Operation {
+	return Operation{ID: Hash(body), Name: name, Type: "query", Body: body}
+}
+
+func writeManifest(t *testing.T, manifest Manifest) string {
+	t.Helper()
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadManifest(t *testing.T) {
+	posts := operation("Posts", "query Posts { posts { id } }")
+
+	tests := []struct {
+		name     string
+		manifest Manifest
+		wantErr  string
+	}{
+		{
+			name:     "Valid manifest",
+			manifest: Manifest{Format: ManifestFormat, Version: 1, Operations: []Operation{posts}},
+		},
+		{
+			name:     "Unknown format",
+			manifest: Manifest{Format: "relay", Version: 1},
+			wantErr:  "unsupported manifest format",
+		},
+		{
+			name: "Id does not match the body",
+			manifest: Manifest{Format: ManifestFormat, Version: 1, Operations: []Operation{
+				{ID: posts.ID, Name: "Titles", Body: "query Titles { posts { title } }"},
+			}},
+			wantErr: "hash of its body",
+		},
+		{
+			name:     "Duplicate operation",
+			manifest: Manifest{Format: ManifestFormat, Version: 1, Operations: []Operation{posts, posts}},
+			wantErr:  "listed twice",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := LoadManifest(writeManifest(t, tt.manifest))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadManifest() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadManifest() error = %v", err)
+			}
+
+			if got := manifest.Queries()[posts.ID]; got != posts.Body {
+				t.Errorf("Queries()[%s] = %q, want %q", posts.ID, got, posts.Body)
+			}
+		})
+	}
+}
+
+func TestManifest_Validate(t *testing.T) {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Name: "schema.graphqls", Input: testSchema})
+
+	manifest := Manifest{Operations: []Operation{
+		operation("Posts", "query Posts { posts { id } }"),
+		operation("Broken", "query Broken { posts { body } }"),
+		operation("Unknown", "query Unknown { comments { id } }"),
+	}}
+
+	err := manifest.Validate(schema)
+	if err == nil {
+		t.Fatal("Validate() error = nil, want the invalid operations")
+	}
+
+	for _, name := range []string{"Broken", "Unknown"} {
+		if !strings.Contains(err.Error(), "operation "+name) {
+			t.Errorf("Validate() error = %v, want a problem of %s", err, name)
+		}
+	}
+	if strings.Contains(err.Error(), "operation Posts") {
+		t.Errorf("Validate() error = %v, valid operation Posts is reported", err)
+	}
+}
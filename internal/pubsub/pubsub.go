@@ -4,28 +4,151 @@ import (
 	"context"
 	"ozon-tesk-task/internal/transport/graph/model"
 	"sync"
+	"time"
+)
+
+const (
+	replayBufferSize = 100
+	// replayBufferTTL is how long the comments of a post without new ones are kept for replay.
+	replayBufferTTL = 10 * time.Minute
 )
 
 type PubSub struct {
-	commentSubscriptions map[int32][]chan *model.Comment
+	commentSubscriptions map[int32][]*subscriber
+	replayBuffers        map[int32]*replayBuffer
+	history              History
+	lastEviction         time.Time
+	now                  func() time.Time
 	lock                 sync.Mutex
 }
 
-func New() *PubSub {
+// History loads the comments of the post created after the comment since, in the order of their ids.
+type History func(ctx context.Context, postId int32, since int32) ([]*model.Comment, error)
+
+type subscriber struct {
+	ch chan *model.Comment
+	// loading is set while the missed comments are loaded from history, the comments published meanwhile are queued.
+	loading bool
+	queued  []*model.Comment
+	// loaded are the ids delivered from history that have not been published yet.
+	loaded map[int32]struct{}
+}
+
+type replayBuffer struct {
+	entries []replayEntry
+	// seq numbers the comments of the post in the order they were published, which may differ from the order of their ids.
+	seq       uint64
+	published time.Time
+}
+
+type replayEntry struct {
+	seq     uint64
+	comment *model.Comment
+}
+
+// New creates a PubSub that catches up resumed subscribers from history when the comments they missed
+// are no longer kept for replay. Without history such subscribers only get the new comments.
+func New(history History) *PubSub {
 	return &PubSub{
-		commentSubscriptions: make(map[int32][]chan *model.Comment),
+		commentSubscriptions: make(map[int32][]*subscriber),
+		replayBuffers:        make(map[int32]*replayBuffer),
+		history:              history,
+		now:                  time.Now,
 		lock:                 sync.Mutex{},
 	}
 }
 
-func (p *PubSub) Subscribe(ctx context.Context, postId int32) <-chan *model.Comment {
+// Subscribe registers a new subscriber for the post. If since is positive, the comments published after
+// the comment since are delivered first: from the replay buffer if it still has that comment, from history otherwise.
+func (p *PubSub) Subscribe(ctx context.Context, postId int32, since int32) (<-chan *model.Comment, error) {
+	p.lock.Lock()
+
+	missed, ok := p.replay(postId, since)
+	if ok || p.history == nil {
+		defer p.lock.Unlock()
+
+		sub := &subscriber{ch: make(chan *model.Comment, len(missed)+1)}
+		for _, comment := range missed {
+			sub.ch <- comment
+		}
+		p.commentSubscriptions[postId] = append(p.commentSubscriptions[postId], sub)
+
+		return sub.ch, nil
+	}
+
+	sub := &subscriber{loading: true}
+	p.commentSubscriptions[postId] = append(p.commentSubscriptions[postId], sub)
+	p.lock.Unlock()
+
+	missed, err := p.history(ctx, postId, since)
+
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	ch := make(chan *model.Comment, 1)
-	p.commentSubscriptions[postId] = append(p.commentSubscriptions[postId], ch)
+	if err != nil {
+		p.remove(postId, sub)
+		return nil, err
+	}
+
+	sub.loaded = make(map[int32]struct{}, len(missed))
+	for _, comment := range missed {
+		sub.loaded[comment.ID] = struct{}{}
+	}
+	for _, comment := range sub.queued {
+		if _, ok := sub.loaded[comment.ID]; !ok {
+			missed = append(missed, comment)
+		}
+	}
+
+	// Only the loaded comments that are yet to be published have to be skipped later.
+	if buffer, ok := p.replayBuffers[postId]; ok {
+		for _, entry := range buffer.entries {
+			delete(sub.loaded, entry.comment.ID)
+		}
+	}
+
+	sub.ch = make(chan *model.Comment, len(missed)+1)
+	for _, comment := range missed {
+		sub.ch <- comment
+	}
+	sub.loading = false
+	sub.queued = nil
 
-	return ch
+	return sub.ch, nil
+}
+
+// replay returns the comments published after the comment since, if the replay buffer of the post still has it.
+func (p *PubSub) replay(postId int32, since int32) ([]*model.Comment, bool) {
+	if since <= 0 {
+		return nil, true
+	}
+
+	buffer, ok := p.replayBuffers[postId]
+	if !ok {
+		return nil, false
+	}
+
+	// Concurrent comments are not published in the order of their ids, so the comments published after
+	// the comment since are found by their sequence rather than by a greater id.
+	var after uint64
+	for _, entry := range buffer.entries {
+		if entry.comment.ID == since {
+			after = entry.seq
+			break
+		}
+	}
+	if after == 0 {
+		return nil, false
+	}
+
+	var missed []*model.Comment
+	for _, entry := range buffer.entries {
+		if entry.seq > after {
+			missed = append(missed, entry.comment)
+		}
+	}
+
+	return missed, true
 }
 
 func (p *PubSub) Publish(ctx context.Context, comment *model.Comment) {
@@ -33,31 +156,104 @@ func (p *PubSub) Publish(ctx context.Context, comment *model.Comment) {
 		p.lock.Lock()
 		defer p.lock.Unlock()
 
-		if subscribers, ok := p.commentSubscriptions[comment.PostID]; ok {
-			for _, ch := range subscribers {
-				ch <- comment
+		now := p.now()
+		p.evictIdle(now)
+
+		buffer, ok := p.replayBuffers[comment.PostID]
+		if !ok {
+			buffer = &replayBuffer{}
+			p.replayBuffers[comment.PostID] = buffer
+		}
+		buffer.add(comment, now)
+
+		for _, sub := range p.commentSubscriptions[comment.PostID] {
+			if sub.loading {
+				sub.queued = append(sub.queued, comment)
+				continue
+			}
+
+			if _, ok := sub.loaded[comment.ID]; ok {
+				delete(sub.loaded, comment.ID)
+				continue
 			}
+
+			sub.ch <- comment
 		}
 	}()
 }
 
+// Evict drops the comments of the post kept for replay, e.g. when the post is deleted.
+func (p *PubSub) Evict(ctx context.Context, postId int32) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.replayBuffers, postId)
+}
+
+// evictIdle drops the replay buffers of the posts without new comments for replayBufferTTL.
+// The buffers are checked at most once per replayBufferTTL.
+func (p *PubSub) evictIdle(now time.Time) {
+	if now.Sub(p.lastEviction) < replayBufferTTL {
+		return
+	}
+	p.lastEviction = now
+
+	for postId, buffer := range p.replayBuffers {
+		if now.Sub(buffer.published) >= replayBufferTTL {
+			delete(p.replayBuffers, postId)
+		}
+	}
+}
+
 func (p *PubSub) Check(postId int32) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
 	_, exists := p.commentSubscriptions[postId]
 	return exists
 }
 
-func (p *PubSub) Unsubscribe(ctx context.Context, postId int32, ch chan *model.Comment) {
+// Unsubscribe removes the subscriber and closes its channel. The comments still sent to the channel
+// are drained, so the caller may stop receiving from it.
+func (p *PubSub) Unsubscribe(ctx context.Context, postId int32, ch <-chan *model.Comment) {
+	// Publish may be blocked on sending to the channel while holding the lock.
+	go func() {
+		for range ch {
+		}
+	}()
+
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	var newSubscribers []chan *model.Comment
 	for _, sub := range p.commentSubscriptions[postId] {
-		if sub != ch {
-			newSubscribers = append(newSubscribers, sub)
+		if sub.ch == ch {
+			p.remove(postId, sub)
+			close(sub.ch)
+			return
 		}
 	}
+}
 
+func (p *PubSub) remove(postId int32, sub *subscriber) {
+	var newSubscribers []*subscriber
+	for _, other := range p.commentSubscriptions[postId] {
+		if other != sub {
+			newSubscribers = append(newSubscribers, other)
+		}
+	}
+
+	if newSubscribers == nil {
+		delete(p.commentSubscriptions, postId)
+		return
+	}
 	p.commentSubscriptions[postId] = newSubscribers
+}
 
-	close(ch)
+func (b *replayBuffer) add(comment *model.Comment, now time.Time) {
+	b.seq++
+	b.entries = append(b.entries, replayEntry{seq: b.seq, comment: comment})
+	if len(b.entries) > replayBufferSize {
+		b.entries = b.entries[len(b.entries)-replayBufferSize:]
+	}
+	b.published = now
 }
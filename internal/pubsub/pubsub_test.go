@@ -0,0 +1,298 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"slices"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, ch <-chan *model.Comment) *model.Comment {
+	t.Helper()
+
+	select {
+	case comment := <-ch:
+		return comment
+	case <-time.After(time.Second):
+		t.Fatal("comment was not delivered")
+		return nil
+	}
+}
+
+func subscribe(t *testing.T, p *PubSub, postId int32, since int32) <-chan *model.Comment {
+	t.Helper()
+
+	ch, err := p.Subscribe(context.Background(), postId, since)
+	if err != nil {
+		t.Fatalf("PubSub.Subscribe() error = %v", err)
+	}
+
+	return ch
+}
+
+func expectNothing(t *testing.T, ch <-chan *model.Comment) {
+	t.Helper()
+
+	select {
+	case comment := <-ch:
+		t.Errorf("PubSub.Subscribe() delivered unexpected comment %d", comment.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestPubSub_SubscribeSince(t *testing.T) {
+	ctx := context.Background()
+	p := New(nil)
+
+	live := subscribe(t, p, 1, 0)
+	for id := int32(1); id <= 3; id++ {
+		p.Publish(ctx, &model.Comment{ID: id, PostID: 1})
+		receive(t, live)
+	}
+
+	resumed := subscribe(t, p, 1, 1)
+
+	for _, want := range []int32{2, 3} {
+		if got := receive(t, resumed); got.ID != want {
+			t.Errorf("PubSub.Subscribe() replayed comment %d, want %d", got.ID, want)
+		}
+	}
+
+	p.Publish(ctx, &model.Comment{ID: 4, PostID: 1})
+
+	if got := receive(t, resumed); got.ID != 4 {
+		t.Errorf("PubSub.Subscribe() delivered comment %d, want 4", got.ID)
+	}
+
+	expectNothing(t, resumed)
+}
+
+func TestPubSub_SubscribeSinceOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	p := New(nil)
+
+	// the comment with the greater id is published first
+	live := subscribe(t, p, 1, 0)
+	for _, id := range []int32{2, 1} {
+		p.Publish(ctx, &model.Comment{ID: id, PostID: 1})
+		receive(t, live)
+	}
+
+	resumed := subscribe(t, p, 1, 2)
+
+	if got := receive(t, resumed); got.ID != 1 {
+		t.Errorf("PubSub.Subscribe() replayed comment %d, want 1", got.ID)
+	}
+	expectNothing(t, resumed)
+}
+
+func TestPubSub_SubscribeSinceFromHistory(t *testing.T) {
+	ctx := context.Background()
+
+	stored := func(from, to int32) []*model.Comment {
+		var comments []*model.Comment
+		for id := from; id <= to; id++ {
+			comments = append(comments, &model.Comment{ID: id, PostID: 1})
+		}
+		return comments
+	}
+
+	t.Run("Replay buffer has lost the comment", func(t *testing.T) {
+		var loadedSince int32
+		p := New(func(ctx context.Context, postId int32, since int32) ([]*model.Comment, error) {
+			loadedSince = since
+			return stored(since+1, replayBufferSize+10), nil
+		})
+
+		live := subscribe(t, p, 1, 0)
+		for _, comment := range stored(1, replayBufferSize+10) {
+			p.Publish(ctx, comment)
+			receive(t, live)
+		}
+
+		resumed := subscribe(t, p, 1, 1)
+
+		if loadedSince != 1 {
+			t.Errorf("PubSub.Subscribe() loaded history since %d, want 1", loadedSince)
+		}
+		for want := int32(2); want <= replayBufferSize+10; want++ {
+			if got := receive(t, resumed); got.ID != want {
+				t.Fatalf("PubSub.Subscribe() replayed comment %d, want %d", got.ID, want)
+			}
+		}
+		expectNothing(t, resumed)
+	})
+
+	t.Run("Comments are published while the history is loaded", func(t *testing.T) {
+		loading := make(chan struct{})
+		loaded := make(chan struct{})
+		p := New(func(ctx context.Context, postId int32, since int32) ([]*model.Comment, error) {
+			close(loading)
+			<-loaded
+			// comment 3 is stored, but not published yet
+			return stored(2, 3), nil
+		})
+
+		var (
+			resumed <-chan *model.Comment
+			err     error
+		)
+		subscribed := make(chan struct{})
+		go func() {
+			defer close(subscribed)
+			resumed, err = p.Subscribe(ctx, 1, 1)
+		}()
+
+		<-loading
+		p.Publish(ctx, &model.Comment{ID: 2, PostID: 1})
+		p.Publish(ctx, &model.Comment{ID: 4, PostID: 1})
+		// the publishing goroutines queue the comments before the history is done
+		time.Sleep(50 * time.Millisecond)
+		close(loaded)
+
+		<-subscribed
+		if err != nil {
+			t.Fatalf("PubSub.Subscribe() error = %v", err)
+		}
+		p.Publish(ctx, &model.Comment{ID: 3, PostID: 1})
+		p.Publish(ctx, &model.Comment{ID: 5, PostID: 1})
+
+		var got []int32
+		for len(got) < 4 {
+			got = append(got, receive(t, resumed).ID)
+		}
+		if want := []int32{2, 3, 4, 5}; !slices.Equal(got, want) {
+			t.Errorf("PubSub.Subscribe() delivered %v, want %v", got, want)
+		}
+		expectNothing(t, resumed)
+	})
+
+	t.Run("History fails", func(t *testing.T) {
+		wantErr := errors.New("database is down")
+		p := New(func(ctx context.Context, postId int32, since int32) ([]*model.Comment, error) {
+			return nil, wantErr
+		})
+
+		if _, err := p.Subscribe(ctx, 1, 1); !errors.Is(err, wantErr) {
+			t.Errorf("PubSub.Subscribe() error = %v, want %v", err, wantErr)
+		}
+		if p.Check(1) {
+			t.Error("PubSub.Check() = true after a failed subscription")
+		}
+	})
+}
+
+func TestPubSub_CheckWhileSubscribing(t *testing.T) {
+	ctx := context.Background()
+	p := New(nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for postId := int32(1); postId <= 100; postId++ {
+			p.Subscribe(ctx, postId, 0)
+		}
+	}()
+
+	for postId := int32(1); postId <= 100; postId++ {
+		p.Check(postId)
+	}
+	<-done
+
+	if !p.Check(100) {
+		t.Error("PubSub.Check() = false for a post with a subscriber")
+	}
+}
+
+func TestPubSub_ReplayBufferEviction(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		evict func(p *PubSub, now *time.Time)
+	}{
+		{
+			name: "Post is deleted",
+			evict: func(p *PubSub, now *time.Time) {
+				p.Evict(ctx, 1)
+			},
+		},
+		{
+			name: "Post is idle",
+			evict: func(p *PubSub, now *time.Time) {
+				*now = now.Add(replayBufferTTL)
+
+				// Comments of other posts trigger the eviction.
+				other := subscribe(t, p, 2, 0)
+				p.Publish(ctx, &model.Comment{ID: 10, PostID: 2})
+				receive(t, other)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			p := New(nil)
+			p.now = func() time.Time { return now }
+
+			live := subscribe(t, p, 1, 0)
+			p.Publish(ctx, &model.Comment{ID: 1, PostID: 1})
+			receive(t, live)
+
+			tt.evict(p, &now)
+
+			p.lock.Lock()
+			_, ok := p.replayBuffers[1]
+			p.lock.Unlock()
+
+			if ok {
+				t.Error("PubSub keeps the replay buffer of the post")
+			}
+		})
+	}
+}
+
+func TestPubSub_Unsubscribe(t *testing.T) {
+	ctx := context.Background()
+	p := New(nil)
+
+	ch := subscribe(t, p, 1, 0)
+	p.Unsubscribe(ctx, 1, ch)
+
+	if _, ok := <-ch; ok {
+		t.Error("PubSub.Unsubscribe() did not close the channel")
+	}
+	if p.Check(1) {
+		t.Error("PubSub.Check() = true for a post without subscribers")
+	}
+}
+
+func TestPubSub_UnsubscribeWhilePublishing(t *testing.T) {
+	ctx := context.Background()
+	p := New(nil)
+
+	ch := subscribe(t, p, 1, 0)
+	other := subscribe(t, p, 1, 0)
+
+	// Nobody reads ch, the second comment blocks Publish on sending to it.
+	for id := int32(1); id <= 2; id++ {
+		p.Publish(ctx, &model.Comment{ID: id, PostID: 1})
+	}
+	receive(t, other)
+
+	unsubscribed := make(chan struct{})
+	go func() {
+		defer close(unsubscribed)
+		p.Unsubscribe(ctx, 1, ch)
+	}()
+
+	select {
+	case <-unsubscribed:
+	case <-time.After(time.Second):
+		t.Fatal("PubSub.Unsubscribe() is blocked by a publish to the channel")
+	}
+
+	receive(t, other)
+}
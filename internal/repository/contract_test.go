@@ -0,0 +1,985 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"ozon-tesk-task/internal/config"
+	"ozon-tesk-task/internal/database"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/internal/service"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type backend struct {
+	name string
+	new  func(t *testing.T) service.Repository
+}
+
+var backends = []backend{
+	{
+		name: "memory",
+		new: func(t *testing.T) service.Repository {
+			return repository.NewMemory()
+		},
+	},
+	{
+		name: "sqlite",
+		new: func(t *testing.T) service.Repository {
+			name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+			dsn := fmt.Sprintf("file:/%s?mode=memory&cache=shared&_pragma=foreign_keys(1)&_time_format=sqlite", name)
+
+			return repository.New(newSQLDatabase(t, "sqlite", dsn, "sqlite://"+dsn))
+		},
+	},
+}
+
+func newSQLDatabase(t *testing.T, driver, dsn, dbURL string) *database.Database {
+	t.Helper()
+
+	ctx := context.Background()
+
+	db := database.New(&config.Config{}, driver, dbURL)
+	if err := db.Connect(ctx, dsn); err != nil {
+		t.Fatalf("failed to connect to %s: %v", driver, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.MigrateUp(ctx); err != nil && !errors.Is(err, database.MigrationNoChange) {
+		t.Fatalf("failed to migrate %s: %v", driver, err)
+	}
+
+	return db
+}
+
+func createPost(t *testing.T, repo service.Repository, title string) int32 {
+	t.Helper()
+
+	id, err := repo.CreatePost(context.Background(), &model.Post{
+		Title:         title,
+		Content:       "content",
+		AllowComments: true,
+		CreatedAt:     parseTime(t, "2025-01-01 00:00:00"),
+	})
+	if err != nil {
+		t.Fatalf("CreatePost() error = %v", err)
+	}
+
+	return id
+}
+
+func parseTime(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	parsed, err := time.Parse(time.DateTime, value)
+	if err != nil {
+		t.Fatalf("time.Parse() error = %v", err)
+	}
+
+	return parsed
+}
+
+func createComment(t *testing.T, repo service.Repository, postId int32, parentId *int32, createdAt string) int32 {
+	t.Helper()
+
+	id, err := repo.CreateComment(context.Background(), &model.Comment{
+		PostID:    postId,
+		ParentID:  parentId,
+		Content:   "comment",
+		CreatedAt: parseTime(t, createdAt),
+	})
+	if err != nil {
+		t.Fatalf("CreateComment() error = %v", err)
+	}
+
+	return id
+}
+
+func commentIds(comments []*model.Comment) []int32 {
+	ids := make([]int32, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+	}
+
+	return ids
+}
+
+func postIds(posts []*model.Post) []int32 {
+	ids := make([]int32, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+
+	return ids
+}
+
+func equalIds(got, want []int32) bool {
+	if len(got) != len(want) {
+		return false
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestRepositoryContract(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo service.Repository)
+	}{
+		{
+			name: "create and get post",
+			run: func(t *testing.T, repo service.Repository) {
+				id := createPost(t, repo, "title")
+
+				post, err := repo.GetPostById(ctx, id)
+				if err != nil {
+					t.Fatalf("GetPostById() error = %v", err)
+				}
+
+				if post.ID != id || post.Title != "title" || post.Content != "content" || !post.AllowComments {
+					t.Errorf("GetPostById() = %+v", post)
+				}
+			},
+		},
+		{
+			name: "get unknown post",
+			run: func(t *testing.T, repo service.Repository) {
+				if _, err := repo.GetPostById(ctx, 42); !errors.Is(err, repository.ErrWrongPostId) {
+					t.Errorf("GetPostById() error = %v, want %v", err, repository.ErrWrongPostId)
+				}
+
+				if _, err := repo.GetPostByIdWithComments(ctx, 42); !errors.Is(err, repository.ErrWrongPostId) {
+					t.Errorf("GetPostByIdWithComments() error = %v, want %v", err, repository.ErrWrongPostId)
+				}
+			},
+		},
+		{
+			name: "list posts",
+			run: func(t *testing.T, repo service.Repository) {
+				if _, err := repo.ListPosts(ctx, 10, 0); !errors.Is(err, repository.ErrNotFound) {
+					t.Errorf("ListPosts() error = %v, want %v", err, repository.ErrNotFound)
+				}
+
+				for _, title := range []string{"first", "second", "third"} {
+					createPost(t, repo, title)
+				}
+
+				posts, err := repo.ListPosts(ctx, 2, 1)
+				if err != nil {
+					t.Fatalf("ListPosts() error = %v", err)
+				}
+
+				if len(posts) != 2 {
+					t.Errorf("ListPosts() returned %d posts, want 2", len(posts))
+				}
+			},
+		},
+		{
+			name: "comments tree",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "title")
+
+				if _, err := repo.GetCommentsByPostId(ctx, postId, repository.UnlimitedDepth, 10, 0); !errors.Is(err, repository.ErrNotFound) {
+					t.Errorf("GetCommentsByPostId() error = %v, want %v", err, repository.ErrNotFound)
+				}
+
+				root := createComment(t, repo, postId, nil, "2025-01-01 00:00:01")
+				reply := createComment(t, repo, postId, &root, "2025-01-01 00:00:02")
+
+				comments, err := repo.GetCommentsByPostId(ctx, postId, repository.UnlimitedDepth, 10, 0)
+				if err != nil {
+					t.Fatalf("GetCommentsByPostId() error = %v", err)
+				}
+
+				if !equalIds(commentIds(comments), []int32{root}) || !equalIds(commentIds(comments[0].Replies), []int32{reply}) {
+					t.Errorf("GetCommentsByPostId() returned unexpected tree %+v", comments)
+				}
+
+				post, err := repo.GetPostByIdWithComments(ctx, postId)
+				if err != nil {
+					t.Fatalf("GetPostByIdWithComments() error = %v", err)
+				}
+
+				if !equalIds(commentIds(post.Comments), []int32{root}) || !equalIds(commentIds(post.Comments[0].Replies), []int32{reply}) {
+					t.Errorf("GetPostByIdWithComments() returned unexpected tree %+v", post.Comments)
+				}
+			},
+		},
+		{
+			name: "get comment",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "title")
+				id := createComment(t, repo, postId, nil, "2025-01-01 00:00:01")
+
+				comment, err := repo.GetCommentById(ctx, id)
+				if err != nil {
+					t.Fatalf("GetCommentById() error = %v", err)
+				}
+
+				if comment.ID != id || comment.PostID != postId || comment.Content != "comment" {
+					t.Errorf("GetCommentById() = %+v", comment)
+				}
+
+				if _, err := repo.GetCommentById(ctx, id+1); !errors.Is(err, repository.ErrWrongCommentId) {
+					t.Errorf("GetCommentById() error = %v, want %v", err, repository.ErrWrongCommentId)
+				}
+			},
+		},
+		{
+			name: "get by ids",
+			run: func(t *testing.T, repo service.Repository) {
+				first := createPost(t, repo, "first")
+				second := createPost(t, repo, "second")
+				comment := createComment(t, repo, first, nil, "2025-01-01 00:00:01")
+
+				posts, err := repo.GetPostsByIds(ctx, []int32{second, first, second + 1})
+				if err != nil {
+					t.Fatalf("GetPostsByIds() error = %v", err)
+				}
+
+				ids := make([]int32, 0, len(posts))
+				for _, post := range posts {
+					ids = append(ids, post.ID)
+				}
+				slices.Sort(ids)
+
+				if !equalIds(ids, []int32{first, second}) {
+					t.Errorf("GetPostsByIds() ids = %v, want %v", ids, []int32{first, second})
+				}
+
+				comments, err := repo.GetCommentsByIds(ctx, []int32{comment, comment + 1})
+				if err != nil {
+					t.Fatalf("GetCommentsByIds() error = %v", err)
+				}
+
+				if !equalIds(commentIds(comments), []int32{comment}) || comments[0].PostID != first {
+					t.Errorf("GetCommentsByIds() = %+v", comments)
+				}
+			},
+		},
+		{
+			name: "get comments after",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "title")
+				otherPostId := createPost(t, repo, "other")
+				first := createComment(t, repo, postId, nil, "2025-01-01 00:00:03")
+				second := createComment(t, repo, postId, &first, "2025-01-01 00:00:01")
+				createComment(t, repo, otherPostId, nil, "2025-01-01 00:00:02")
+				third := createComment(t, repo, postId, nil, "2025-01-01 00:00:02")
+
+				comments, err := repo.GetCommentsAfter(ctx, postId, first)
+				if err != nil {
+					t.Fatalf("GetCommentsAfter() error = %v", err)
+				}
+				if !equalIds(commentIds(comments), []int32{second, third}) {
+					t.Errorf("GetCommentsAfter() ids = %v, want %v", commentIds(comments), []int32{second, third})
+				}
+
+				comments, err = repo.GetCommentsAfter(ctx, postId, third)
+				if err != nil || len(comments) != 0 {
+					t.Errorf("GetCommentsAfter() of the last comment = %+v, %v, want none", comments, err)
+				}
+			},
+		},
+		{
+			name: "delete comment",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "title")
+				id := createComment(t, repo, postId, nil, "2025-01-01 00:00:01")
+
+				if err := repo.DeleteComment(ctx, id); err != nil {
+					t.Fatalf("DeleteComment() error = %v", err)
+				}
+
+				if err := repo.DeleteComment(ctx, id); !errors.Is(err, repository.ErrWrongCommentId) {
+					t.Errorf("DeleteComment() error = %v, want %v", err, repository.ErrWrongCommentId)
+				}
+			},
+		},
+		{
+			name: "delete post",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "title")
+				commentId := createComment(t, repo, postId, nil, "2025-01-01 00:00:01")
+
+				if err := repo.DeletePost(ctx, postId); err != nil {
+					t.Fatalf("DeletePost() error = %v", err)
+				}
+
+				if _, err := repo.GetCommentById(ctx, commentId); !errors.Is(err, repository.ErrWrongCommentId) {
+					t.Errorf("GetCommentById() error = %v, want %v", err, repository.ErrWrongCommentId)
+				}
+
+				if err := repo.DeletePost(ctx, postId); !errors.Is(err, repository.ErrWrongPostId) {
+					t.Errorf("DeletePost() error = %v, want %v", err, repository.ErrWrongPostId)
+				}
+			},
+		},
+		{
+			name: "posts pagination and ordering",
+			run: func(t *testing.T, repo service.Repository) {
+				var ids []int32
+				for _, title := range []string{"first", "second", "third", "fourth", "fifth"} {
+					ids = append(ids, createPost(t, repo, title))
+				}
+
+				pages := []struct {
+					limit, offset int32
+					want          []int32
+				}{
+					{limit: 2, offset: 0, want: ids[0:2]},
+					{limit: 2, offset: 2, want: ids[2:4]},
+					{limit: 2, offset: 4, want: ids[4:5]},
+					{limit: 10, offset: 0, want: ids},
+				}
+
+				for _, page := range pages {
+					posts, err := repo.ListPosts(ctx, page.limit, page.offset)
+					if err != nil {
+						t.Fatalf("ListPosts(%d, %d) error = %v", page.limit, page.offset, err)
+					}
+
+					if got := postIds(posts); !equalIds(got, page.want) {
+						t.Errorf("ListPosts(%d, %d) = %v, want %v", page.limit, page.offset, got, page.want)
+					}
+				}
+
+				if _, err := repo.ListPosts(ctx, 2, 5); !errors.Is(err, repository.ErrNotFound) {
+					t.Errorf("ListPosts() error = %v, want %v", err, repository.ErrNotFound)
+				}
+			},
+		},
+		{
+			name: "posts with comments are paginated by posts",
+			run: func(t *testing.T, repo service.Repository) {
+				first := createPost(t, repo, "first")
+				second := createPost(t, repo, "second")
+				third := createPost(t, repo, "third")
+
+				for i := 0; i < 3; i++ {
+					createComment(t, repo, first, nil, fmt.Sprintf("2025-01-01 00:00:0%d", i))
+				}
+				createComment(t, repo, second, nil, "2025-01-01 00:00:05")
+
+				posts, err := repo.ListPostsWithComments(ctx, 2, 0)
+				if err != nil {
+					t.Fatalf("ListPostsWithComments() error = %v", err)
+				}
+
+				if got := postIds(posts); !equalIds(got, []int32{first, second}) {
+					t.Fatalf("ListPostsWithComments() = %v, want %v", got, []int32{first, second})
+				}
+
+				if len(posts[0].Comments) != 3 || len(posts[1].Comments) != 1 {
+					t.Errorf("ListPostsWithComments() returned %d and %d comments, want 3 and 1", len(posts[0].Comments), len(posts[1].Comments))
+				}
+
+				posts, err = repo.ListPostsWithComments(ctx, 2, 2)
+				if err != nil {
+					t.Fatalf("ListPostsWithComments() error = %v", err)
+				}
+
+				if got := postIds(posts); !equalIds(got, []int32{third}) || len(posts[0].Comments) != 0 {
+					t.Errorf("ListPostsWithComments() = %v with %d comments, want %v without comments", got, len(posts[0].Comments), []int32{third})
+				}
+			},
+		},
+		{
+			name: "comments pagination and ordering",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "title")
+
+				late := createComment(t, repo, postId, nil, "2025-01-01 00:00:03")
+				early := createComment(t, repo, postId, nil, "2025-01-01 00:00:01")
+				middle := createComment(t, repo, postId, nil, "2025-01-01 00:00:02")
+
+				comments, err := repo.GetCommentsByPostId(ctx, postId, repository.UnlimitedDepth, 2, 0)
+				if err != nil {
+					t.Fatalf("GetCommentsByPostId() error = %v", err)
+				}
+
+				if got := commentIds(comments); !equalIds(got, []int32{early, middle}) {
+					t.Errorf("GetCommentsByPostId() = %v, want %v", got, []int32{early, middle})
+				}
+
+				comments, err = repo.GetCommentsByPostId(ctx, postId, repository.UnlimitedDepth, 2, 2)
+				if err != nil {
+					t.Fatalf("GetCommentsByPostId() error = %v", err)
+				}
+
+				if got := commentIds(comments); !equalIds(got, []int32{late}) {
+					t.Errorf("GetCommentsByPostId() = %v, want %v", got, []int32{late})
+				}
+			},
+		},
+		{
+			name: "parent ids",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "title")
+				root := createComment(t, repo, postId, nil, "2025-01-01 00:00:01")
+				reply := createComment(t, repo, postId, &root, "2025-01-01 00:00:02")
+
+				comment, err := repo.GetCommentById(ctx, root)
+				if err != nil {
+					t.Fatalf("GetCommentById() error = %v", err)
+				}
+
+				if comment.ParentID != nil {
+					t.Errorf("GetCommentById() parent = %d, want nil", *comment.ParentID)
+				}
+
+				comments, err := repo.GetCommentsByPostId(ctx, postId, repository.UnlimitedDepth, 10, 0)
+				if err != nil {
+					t.Fatalf("GetCommentsByPostId() error = %v", err)
+				}
+
+				if comments[0].ParentID != nil {
+					t.Errorf("GetCommentsByPostId() root parent = %d, want nil", *comments[0].ParentID)
+				}
+
+				if got := comments[0].Replies[0]; got.ID != reply || got.ParentID == nil || *got.ParentID != root {
+					t.Errorf("GetCommentsByPostId() reply = %+v, want parent %d", got, root)
+				}
+			},
+		},
+		{
+			name: "deep tree",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "title")
+
+				ids := []int32{createComment(t, repo, postId, nil, "2025-01-01 00:00:00")}
+				for i := 1; i < 5; i++ {
+					parent := ids[i-1]
+					ids = append(ids, createComment(t, repo, postId, &parent, fmt.Sprintf("2025-01-01 00:00:0%d", i)))
+				}
+				sibling := createComment(t, repo, postId, &ids[0], "2025-01-01 00:00:09")
+
+				post, err := repo.GetPostByIdWithComments(ctx, postId)
+				if err != nil {
+					t.Fatalf("GetPostByIdWithComments() error = %v", err)
+				}
+
+				level := post.Comments
+				for depth, id := range ids {
+					if len(level) == 0 || level[0].ID != id {
+						t.Fatalf("GetPostByIdWithComments() depth %d = %v, want %d first", depth, commentIds(level), id)
+					}
+
+					if depth == 1 && !equalIds(commentIds(level), []int32{ids[1], sibling}) {
+						t.Errorf("GetPostByIdWithComments() depth 1 = %v, want %v", commentIds(level), []int32{ids[1], sibling})
+					}
+
+					level = level[0].Replies
+				}
+			},
+		},
+		{
+			name: "comment thread",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "title")
+
+				root := createComment(t, repo, postId, nil, "2025-01-01 00:00:00")
+				ids := []int32{createComment(t, repo, postId, &root, "2025-01-01 00:00:01")}
+				for i := 1; i < 4; i++ {
+					parent := ids[i-1]
+					ids = append(ids, createComment(t, repo, postId, &parent, fmt.Sprintf("2025-01-01 00:00:0%d", i+1)))
+				}
+				sibling := createComment(t, repo, postId, &root, "2025-01-01 00:00:08")
+				other := createComment(t, repo, postId, nil, "2025-01-01 00:00:09")
+
+				if _, err := repo.GetCommentThread(ctx, 100, repository.UnlimitedDepth); !errors.Is(err, repository.ErrWrongCommentId) {
+					t.Errorf("GetCommentThread() error = %v, want %v", err, repository.ErrWrongCommentId)
+				}
+
+				thread, err := repo.GetCommentThread(ctx, ids[0], 2)
+				if err != nil {
+					t.Fatalf("GetCommentThread() error = %v", err)
+				}
+
+				if thread.ID != ids[0] || thread.Depth != 1 {
+					t.Fatalf("GetCommentThread() = %+v, want comment %d at depth 1", thread, ids[0])
+				}
+
+				level := thread.Replies
+				for _, id := range ids[1:3] {
+					if !equalIds(commentIds(level), []int32{id}) {
+						t.Fatalf("GetCommentThread() level = %v, want %v", commentIds(level), []int32{id})
+					}
+					level = level[0].Replies
+				}
+
+				if len(level) != 0 {
+					t.Errorf("GetCommentThread() returned %v below the max depth", commentIds(level))
+				}
+
+				thread, err = repo.GetCommentThread(ctx, root, 1)
+				if err != nil {
+					t.Fatalf("GetCommentThread() error = %v", err)
+				}
+
+				if got := commentIds(thread.Replies); !equalIds(got, []int32{ids[0], sibling}) {
+					t.Errorf("GetCommentThread() replies = %v, want %v", got, []int32{ids[0], sibling})
+				}
+
+				comments, err := repo.GetCommentsByPostId(ctx, postId, 0, 10, 0)
+				if err != nil {
+					t.Fatalf("GetCommentsByPostId() error = %v", err)
+				}
+
+				if got := commentIds(comments); !equalIds(got, []int32{root, other}) || len(comments[0].Replies) != 0 {
+					t.Errorf("GetCommentsByPostId() = %v, want %v without replies", got, []int32{root, other})
+				}
+			},
+		},
+		{
+			name: "quoted comment",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "title")
+				root := createComment(t, repo, postId, nil, "2025-01-01 00:00:01")
+				quoted := createComment(t, repo, postId, &root, "2025-01-01 00:00:02")
+
+				id, err := repo.CreateComment(ctx, &model.Comment{PostID: postId, ParentID: &root, QuotedID: &quoted, Content: "comment"})
+				if err != nil {
+					t.Fatalf("CreateComment() error = %v", err)
+				}
+
+				comment, err := repo.GetCommentById(ctx, id)
+				if err != nil {
+					t.Fatalf("GetCommentById() error = %v", err)
+				}
+
+				if comment.QuotedID == nil || *comment.QuotedID != quoted {
+					t.Errorf("GetCommentById() quoted = %v, want %d", comment.QuotedID, quoted)
+				}
+
+				if err := repo.DeleteComment(ctx, quoted); err != nil {
+					t.Fatalf("DeleteComment() error = %v", err)
+				}
+
+				comment, err = repo.GetCommentById(ctx, id)
+				if err != nil {
+					t.Fatalf("GetCommentById() error = %v", err)
+				}
+
+				if comment.QuotedID != nil {
+					t.Errorf("GetCommentById() quoted = %d after the quoted comment was deleted, want nil", *comment.QuotedID)
+				}
+			},
+		},
+		{
+			name: "reply outside of the page",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "title")
+				root := createComment(t, repo, postId, nil, "2025-01-01 00:00:01")
+				reply := createComment(t, repo, postId, &root, "2025-01-01 00:00:02")
+
+				comments, err := repo.GetCommentsByPostId(ctx, postId, repository.UnlimitedDepth, 1, 1)
+				if err != nil {
+					t.Fatalf("GetCommentsByPostId() error = %v", err)
+				}
+
+				if got := commentIds(comments); !equalIds(got, []int32{reply}) {
+					t.Errorf("GetCommentsByPostId() = %v, want %v", got, []int32{reply})
+				}
+			},
+		},
+		{
+			name: "create comment error mapping",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "title")
+
+				_, err := repo.CreateComment(ctx, &model.Comment{PostID: postId + 1, Content: "comment"})
+				if !errors.Is(err, repository.ErrWrongPostId) {
+					t.Errorf("CreateComment() error = %v, want %v", err, repository.ErrWrongPostId)
+				}
+
+				parentId := int32(42)
+				_, err = repo.CreateComment(ctx, &model.Comment{PostID: postId, ParentID: &parentId, Content: "comment"})
+				if !errors.Is(err, repository.ErrWrongCommentId) {
+					t.Errorf("CreateComment() error = %v, want %v", err, repository.ErrWrongCommentId)
+				}
+			},
+		},
+		{
+			name: "delete comment with replies",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "title")
+				root := createComment(t, repo, postId, nil, "2025-01-01 00:00:01")
+				reply := createComment(t, repo, postId, &root, "2025-01-01 00:00:02")
+
+				if err := repo.DeleteComment(ctx, root); !errors.Is(err, repository.ErrCommentHasReplies) {
+					t.Errorf("DeleteComment() error = %v, want %v", err, repository.ErrCommentHasReplies)
+				}
+
+				if err := repo.DeleteComment(ctx, reply); err != nil {
+					t.Fatalf("DeleteComment() error = %v", err)
+				}
+
+				if err := repo.DeleteComment(ctx, root); err != nil {
+					t.Errorf("DeleteComment() error = %v", err)
+				}
+			},
+		},
+		{
+			name: "transaction commit and rollback",
+			run: func(t *testing.T, repo service.Repository) {
+				errRollback := errors.New("rollback")
+
+				var rolledBack int32
+				err := repo.WithinTransaction(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+					id, err := repo.CreatePost(ctx, &model.Post{Title: "rolled back", Content: "content"})
+					if err != nil {
+						t.Fatalf("CreatePost() error = %v", err)
+					}
+					rolledBack = id
+
+					if _, err := repo.GetPostById(ctx, rolledBack); err != nil {
+						t.Errorf("GetPostById() inside transaction error = %v", err)
+					}
+
+					return errRollback
+				})
+				if !errors.Is(err, errRollback) {
+					t.Fatalf("WithinTransaction() error = %v, want %v", err, errRollback)
+				}
+
+				if _, err := repo.GetPostById(ctx, rolledBack); !errors.Is(err, repository.ErrWrongPostId) {
+					t.Errorf("GetPostById() error = %v, want %v", err, repository.ErrWrongPostId)
+				}
+
+				var committed int32
+				err = repo.WithinTransaction(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+					return repo.WithinTransaction(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+						id, err := repo.CreatePost(ctx, &model.Post{Title: "committed", Content: "content"})
+						committed = id
+						return err
+					})
+				})
+				if err != nil {
+					t.Fatalf("WithinTransaction() error = %v", err)
+				}
+
+				if _, err := repo.GetPostById(ctx, committed); err != nil {
+					t.Errorf("GetPostById() error = %v", err)
+				}
+			},
+		},
+		{
+			name: "rollback of deletes",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "post")
+				commentId, err := repo.CreateComment(ctx, &model.Comment{PostID: postId, Content: "comment"})
+				if err != nil {
+					t.Fatalf("CreateComment() error = %v", err)
+				}
+
+				errRollback := errors.New("rollback")
+
+				err = repo.WithinTransaction(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+					if err := repo.DeleteComment(ctx, commentId); err != nil {
+						t.Fatalf("DeleteComment() error = %v", err)
+					}
+					if err := repo.DeletePost(ctx, postId); err != nil {
+						t.Fatalf("DeletePost() error = %v", err)
+					}
+					if _, err := repo.CreatePost(ctx, &model.Post{Title: "rolled back", Content: "content"}); err != nil {
+						t.Fatalf("CreatePost() error = %v", err)
+					}
+
+					return errRollback
+				})
+				if !errors.Is(err, errRollback) {
+					t.Fatalf("WithinTransaction() error = %v, want %v", err, errRollback)
+				}
+
+				posts, err := repo.ListPosts(ctx, 10, 0)
+				if err != nil {
+					t.Fatalf("ListPosts() error = %v", err)
+				}
+				if len(posts) != 1 || posts[0].ID != postId || posts[0].CommentCount != 1 {
+					t.Errorf("ListPosts() = %+v, want the post with its comment", posts)
+				}
+
+				if _, err := repo.GetCommentById(ctx, commentId); err != nil {
+					t.Errorf("GetCommentById() error = %v", err)
+				}
+			},
+		},
+		{
+			name: "savepoint",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "post")
+
+				var kept, dropped int32
+
+				err := repo.WithinTransaction(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+					err := repo.WithinSavepoint(ctx, func(ctx context.Context) error {
+						var err error
+						kept, err = repo.CreateComment(ctx, &model.Comment{PostID: postId, Content: "kept"})
+						return err
+					})
+					if err != nil {
+						return err
+					}
+
+					err = repo.WithinSavepoint(ctx, func(ctx context.Context) error {
+						var err error
+						if dropped, err = repo.CreateComment(ctx, &model.Comment{PostID: postId, Content: "dropped"}); err != nil {
+							return err
+						}
+
+						wrongParent := int32(100)
+						_, err = repo.CreateComment(ctx, &model.Comment{PostID: postId, ParentID: &wrongParent, Content: "comment"})
+						return err
+					})
+					if !errors.Is(err, repository.ErrWrongCommentId) {
+						t.Errorf("WithinSavepoint() error = %v, want %v", err, repository.ErrWrongCommentId)
+					}
+
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("WithinTransaction() error = %v", err)
+				}
+
+				if _, err := repo.GetCommentById(ctx, kept); err != nil {
+					t.Errorf("GetCommentById() of kept comment error = %v", err)
+				}
+
+				if _, err := repo.GetCommentById(ctx, dropped); !errors.Is(err, repository.ErrWrongCommentId) {
+					t.Errorf("GetCommentById() of dropped comment error = %v, want %v", err, repository.ErrWrongCommentId)
+				}
+
+				post, err := repo.GetPostById(ctx, postId)
+				if err != nil {
+					t.Fatalf("GetPostById() error = %v", err)
+				}
+
+				if post.CommentCount != 1 {
+					t.Errorf("CommentCount = %d, want 1", post.CommentCount)
+				}
+			},
+		},
+		{
+			name: "idempotency records",
+			run: func(t *testing.T, repo service.Repository) {
+				now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+				record := &repository.IdempotencyRecord{
+					Scope:       "user:1",
+					Key:         "key",
+					RequestHash: "hash",
+					Response:    []byte(`{"id":1}`),
+					CreatedAt:   now,
+					ExpiresAt:   now.Add(time.Hour),
+				}
+
+				if err := repo.SaveIdempotencyRecord(ctx, record); err != nil {
+					t.Fatalf("SaveIdempotencyRecord() error = %v", err)
+				}
+
+				got, err := repo.GetIdempotencyRecord(ctx, "user:1", "key", now.Add(time.Minute))
+				if err != nil {
+					t.Fatalf("GetIdempotencyRecord() error = %v", err)
+				}
+				if got.RequestHash != "hash" || string(got.Response) != `{"id":1}` || !got.ExpiresAt.Equal(record.ExpiresAt) {
+					t.Errorf("GetIdempotencyRecord() = %+v", got)
+				}
+
+				if _, err := repo.GetIdempotencyRecord(ctx, "client:1.1.1.1/1", "key", now); !errors.Is(err, repository.ErrNotFound) {
+					t.Errorf("GetIdempotencyRecord() of another client error = %v, want %v", err, repository.ErrNotFound)
+				}
+
+				if _, err := repo.GetIdempotencyRecord(ctx, "user:1", "key", now.Add(time.Hour)); !errors.Is(err, repository.ErrNotFound) {
+					t.Errorf("GetIdempotencyRecord() of expired key error = %v, want %v", err, repository.ErrNotFound)
+				}
+
+				if err := repo.SaveIdempotencyRecord(ctx, record); !errors.Is(err, repository.ErrIdempotencyKeyInUse) {
+					t.Errorf("SaveIdempotencyRecord() of existing key error = %v, want %v", err, repository.ErrIdempotencyKeyInUse)
+				}
+
+				renewed := *record
+				renewed.CreatedAt = now.Add(2 * time.Hour)
+				renewed.ExpiresAt = now.Add(3 * time.Hour)
+				if err := repo.SaveIdempotencyRecord(ctx, &renewed); err != nil {
+					t.Errorf("SaveIdempotencyRecord() of expired key error = %v", err)
+				}
+
+				if deleted, err := repo.DeleteExpiredIdempotencyRecords(ctx, now.Add(3*time.Hour)); err != nil || deleted != 1 {
+					t.Errorf("DeleteExpiredIdempotencyRecords() = %d, %v, want 1", deleted, err)
+				}
+			},
+		},
+		{
+			name: "timestamps",
+			run: func(t *testing.T, repo service.Repository) {
+				createdAt := time.Date(2025, 1, 2, 3, 4, 5, 123456000, time.UTC)
+
+				postId, err := repo.CreatePost(ctx, &model.Post{Title: "post", Content: "content", AllowComments: true, CreatedAt: createdAt})
+				if err != nil {
+					t.Fatalf("CreatePost() error = %v", err)
+				}
+
+				commentId, err := repo.CreateComment(ctx, &model.Comment{PostID: postId, Content: "comment", CreatedAt: createdAt.Add(time.Hour)})
+				if err != nil {
+					t.Fatalf("CreateComment() error = %v", err)
+				}
+
+				post, err := repo.GetPostByIdWithComments(ctx, postId)
+				if err != nil {
+					t.Fatalf("GetPostByIdWithComments() error = %v", err)
+				}
+
+				if !post.CreatedAt.Equal(createdAt) {
+					t.Errorf("Post.CreatedAt = %v, want %v", post.CreatedAt, createdAt)
+				}
+
+				if !post.LastActivityAt.Equal(createdAt.Add(time.Hour)) {
+					t.Errorf("Post.LastActivityAt = %v, want %v", post.LastActivityAt, createdAt.Add(time.Hour))
+				}
+
+				if len(post.Comments) != 1 || !post.Comments[0].CreatedAt.Equal(createdAt.Add(time.Hour)) {
+					t.Errorf("Post.Comments = %+v", post.Comments)
+				}
+
+				comment, err := repo.GetCommentById(ctx, commentId)
+				if err != nil {
+					t.Fatalf("GetCommentById() error = %v", err)
+				}
+
+				if !comment.CreatedAt.Equal(createdAt.Add(time.Hour)) {
+					t.Errorf("Comment.CreatedAt = %v, want %v", comment.CreatedAt, createdAt.Add(time.Hour))
+				}
+			},
+		},
+		{
+			name: "post activity",
+			run: func(t *testing.T, repo service.Repository) {
+				postId := createPost(t, repo, "post")
+
+				assertActivity := func(count int32, lastActivityAt func(post *model.Post) time.Time) {
+					t.Helper()
+
+					post, err := repo.GetPostById(ctx, postId)
+					if err != nil {
+						t.Fatalf("GetPostById() error = %v", err)
+					}
+
+					if post.CommentCount != count {
+						t.Errorf("CommentCount = %d, want %d", post.CommentCount, count)
+					}
+
+					if want := lastActivityAt(post); !post.LastActivityAt.Equal(want) {
+						t.Errorf("LastActivityAt = %v, want %v", post.LastActivityAt, want)
+					}
+
+					posts, err := repo.ListPostsWithComments(ctx, 10, 0)
+					if err != nil {
+						t.Fatalf("ListPostsWithComments() error = %v", err)
+					}
+
+					if posts[0].CommentCount != post.CommentCount || !posts[0].LastActivityAt.Equal(post.LastActivityAt) {
+						t.Errorf("ListPostsWithComments() = %+v, want %+v", posts[0], post)
+					}
+				}
+
+				commentCreatedAt := func(id int32) func(post *model.Post) time.Time {
+					return func(*model.Post) time.Time {
+						comment, err := repo.GetCommentById(ctx, id)
+						if err != nil {
+							t.Fatalf("GetCommentById() error = %v", err)
+						}
+
+						return comment.CreatedAt
+					}
+				}
+
+				postCreatedAt := func(post *model.Post) time.Time {
+					return post.CreatedAt
+				}
+
+				assertActivity(0, postCreatedAt)
+
+				first := createComment(t, repo, postId, nil, "2025-01-02 00:00:00")
+				second := createComment(t, repo, postId, nil, "2025-01-03 00:00:00")
+
+				assertActivity(2, commentCreatedAt(second))
+
+				wrongParent := int32(100)
+				if _, err := repo.CreateComment(ctx, &model.Comment{PostID: postId, ParentID: &wrongParent, Content: "comment"}); err == nil {
+					t.Fatal("CreateComment() error = nil")
+				}
+
+				assertActivity(2, commentCreatedAt(second))
+
+				if err := repo.DeleteComment(ctx, second); err != nil {
+					t.Fatalf("DeleteComment() error = %v", err)
+				}
+
+				assertActivity(1, commentCreatedAt(first))
+
+				if err := repo.DeleteComment(ctx, first); err != nil {
+					t.Fatalf("DeleteComment() error = %v", err)
+				}
+
+				assertActivity(0, postCreatedAt)
+			},
+		},
+	}
+
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					tt.run(t, b.new(t))
+				})
+			}
+		})
+	}
+}
+
+func TestRepairPostActivity(t *testing.T) {
+	ctx := context.Background()
+
+	dsn := "file:/repair?mode=memory&cache=shared&_pragma=foreign_keys(1)&_time_format=sqlite"
+	db := newSQLDatabase(t, "sqlite", dsn, "sqlite://"+dsn)
+	repo := repository.New(db)
+
+	broken := createPost(t, repo, "broken")
+	createComment(t, repo, broken, nil, "2025-01-02 00:00:00")
+	createPost(t, repo, "intact")
+
+	if _, err := db.DB.ExecContext(ctx, "UPDATE posts SET comment_count = 5, last_activity_at = NULL WHERE id = $1", broken); err != nil {
+		t.Fatalf("failed to break counters: %v", err)
+	}
+
+	fixed, err := repo.RepairPostActivity(ctx)
+	if err != nil {
+		t.Fatalf("RepairPostActivity() error = %v", err)
+	}
+
+	if fixed != 1 {
+		t.Errorf("RepairPostActivity() = %d, want 1", fixed)
+	}
+
+	post, err := repo.GetPostById(ctx, broken)
+	if err != nil {
+		t.Fatalf("GetPostById() error = %v", err)
+	}
+
+	if post.CommentCount != 1 || post.LastActivityAt.IsZero() {
+		t.Errorf("GetPostById() = %+v", post)
+	}
+}
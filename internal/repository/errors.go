@@ -1,11 +1,96 @@
 package repository
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	postgresForeignKeyViolation  = "23503"
+	postgresSerializationFailure = "40001"
+
+	postgresCommentPostForeignKey   = "comments_post_id_fkey"
+	postgresCommentParentForeignKey = "comments_parent_comment_id_fkey"
+)
+
+// Code is a machine readable error code returned to clients.
+type Code string
+
+const (
+	CodeNotFound        Code = "NOT_FOUND"
+	CodeUnauthenticated Code = "UNAUTHENTICATED"
+	CodeForbidden       Code = "FORBIDDEN"
+	CodeValidation      Code = "VALIDATION"
+	CodeConflict        Code = "CONFLICT"
+	CodeCommentsLocked  Code = "COMMENTS_LOCKED"
+	CodeCommentTooDeep  Code = "COMMENT_TOO_DEEP"
+)
+
+// Error is an error whose message can be shown to clients. Any other error is reported as an internal one.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+func NewError(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
 
 var (
-	ErrNotFound             = errors.New("nothing was found")
-	ErrWrongPostId          = errors.New("post with such id does not exist")
-	ErrWrongCommentId       = errors.New("comment with such id does not exist")
-	ErrCommentsNotAllowed   = errors.New("post with such id does not allow comments")
-	ErrMatchCommentWithPost = errors.New("comment with such id does not belong to the post")
+	ErrNotFound             = NewError(CodeNotFound, "nothing was found")
+	ErrWrongPostId          = NewError(CodeNotFound, "post with such id does not exist")
+	ErrWrongCommentId       = NewError(CodeNotFound, "comment with such id does not exist")
+	ErrCommentsNotAllowed   = NewError(CodeCommentsLocked, "post with such id does not allow comments")
+	ErrMatchCommentWithPost = NewError(CodeValidation, "comment with such id does not belong to the post")
+	ErrCommentHasReplies    = NewError(CodeConflict, "comment with such id has replies")
+	ErrCommentTooDeep       = NewError(CodeCommentTooDeep, "reply exceeds the maximum comment depth")
+	ErrInvalidArgument      = NewError(CodeValidation, "invalid argument")
+	ErrInvalidId            = NewError(CodeValidation, "invalid id")
+	ErrBatchTooLarge        = NewError(CodeValidation, "batch has too many items")
+
+	ErrUnauthenticated = NewError(CodeUnauthenticated, "authorization token is invalid or has expired")
+
+	ErrInvalidIdempotencyKey = NewError(CodeValidation, "idempotency key must be at most 255 characters long")
+	ErrIdempotencyKeyReused  = NewError(CodeConflict, "idempotency key was already used for a different request")
+	ErrIdempotencyKeyInUse   = NewError(CodeConflict, "request with this idempotency key is already in progress")
 )
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == postgresForeignKeyViolation
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+	}
+
+	return false
+}
+
+// foreignKeyConstraint returns the name of the violated constraint if the driver reports it.
+func foreignKeyConstraint(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Constraint
+	}
+
+	return ""
+}
+
+func isSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == postgresSerializationFailure
+	}
+
+	return false
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// IdempotencyRecord is the stored result of a mutation sent with an idempotency key.
+type IdempotencyRecord struct {
+	// Scope is the client that sent the key, see auth.Scope
+	Scope       string
+	Key         string
+	RequestHash string
+	Response    []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+type idempotencyKey struct {
+	scope string
+	key   string
+}
+
+// GetIdempotencyRecord returns the record of the client's key unless it has expired.
+func (r *Repository) GetIdempotencyRecord(ctx context.Context, scope string, key string, now time.Time) (*IdempotencyRecord, error) {
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
+	var (
+		record   IdempotencyRecord
+		response string
+	)
+
+	err := sq.Select("scope", "idempotency_key", "request_hash", "response", "created_at", "expires_at").
+		From("idempotency_keys").
+		Where(sq.Eq{"scope": scope, "idempotency_key": key}).
+		Where(sq.Gt{"expires_at": now}).
+		PlaceholderFormat(sq.Dollar).
+		RunWith(r.runner(ctx)).
+		QueryRowContext(ctx).
+		Scan(&record.Scope, &record.Key, &record.RequestHash, &response, &record.CreatedAt, &record.ExpiresAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record.Response = []byte(response)
+
+	return &record, nil
+}
+
+// SaveIdempotencyRecord stores the record, replacing an expired one with the same key.
+func (r *Repository) SaveIdempotencyRecord(ctx context.Context, record *IdempotencyRecord) error {
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
+	res, err := sq.Insert("idempotency_keys").
+		Columns("scope", "idempotency_key", "request_hash", "response", "created_at", "expires_at").
+		Values(record.Scope, record.Key, record.RequestHash, string(record.Response), record.CreatedAt, record.ExpiresAt).
+		Suffix(`ON CONFLICT (scope, idempotency_key) DO UPDATE SET
+			request_hash = excluded.request_hash,
+			response = excluded.response,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at
+			WHERE idempotency_keys.expires_at <= excluded.created_at`).
+		PlaceholderFormat(sq.Dollar).
+		RunWith(r.runner(ctx)).
+		ExecContext(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	saved, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if saved == 0 {
+		return ErrIdempotencyKeyInUse
+	}
+
+	return nil
+}
+
+// DeleteExpiredIdempotencyRecords deletes the records that have expired by now and returns their number.
+func (r *Repository) DeleteExpiredIdempotencyRecords(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
+	res, err := sq.Delete("idempotency_keys").
+		Where(sq.LtOrEq{"expires_at": now}).
+		PlaceholderFormat(sq.Dollar).
+		RunWith(r.runner(ctx)).
+		ExecContext(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
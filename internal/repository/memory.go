@@ -0,0 +1,592 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"ozon-tesk-task/pkg/pointer"
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryTxKey struct{}
+
+type MemoryRepository struct {
+	posts         map[int32]*model.Post
+	comments      map[int32]*model.Comment
+	postIds       []int32
+	lastPostId    int32
+	lastCommentId int32
+	lock          sync.RWMutex
+
+	idempotencyRecords map[idempotencyKey]*IdempotencyRecord
+
+	// undo reverts the changes of the running transaction in reverse order, transactions are serialized
+	undo []func()
+}
+
+func NewMemory() *MemoryRepository {
+	return &MemoryRepository{
+		posts:    make(map[int32]*model.Post),
+		comments: make(map[int32]*model.Comment),
+
+		idempotencyRecords: make(map[idempotencyKey]*IdempotencyRecord),
+	}
+}
+
+func (r *MemoryRepository) ListPosts(ctx context.Context, limit, offset int32) ([]*model.Post, error) {
+	defer r.readLock(ctx)()
+
+	var posts []*model.Post
+
+	for _, id := range paginate(r.postIds, limit, offset) {
+		posts = append(posts, copyPost(r.posts[id]))
+	}
+
+	if len(posts) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return posts, nil
+}
+
+func (r *MemoryRepository) ListPostsWithComments(ctx context.Context, limit, offset int32) ([]*model.Post, error) {
+	defer r.readLock(ctx)()
+
+	var posts []*model.Post
+
+	for _, id := range paginate(r.postIds, limit, offset) {
+		post := copyPost(r.posts[id])
+		post.Comments = buildCommentsTree(r.postComments(id))
+
+		posts = append(posts, post)
+	}
+
+	if len(posts) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return posts, nil
+}
+
+func (r *MemoryRepository) CreatePost(ctx context.Context, post *model.Post) (int32, error) {
+	defer r.writeLock(ctx)()
+
+	lastPostId, postCount := r.lastPostId, len(r.postIds)
+	r.logUndo(ctx, func() {
+		r.lastPostId = lastPostId
+		r.postIds = r.postIds[:postCount]
+	})
+
+	r.lastPostId++
+
+	stored := copyPost(post)
+	stored.ID = r.lastPostId
+	stored.CommentCount = 0
+	stored.LastActivityAt = stored.CreatedAt
+
+	r.setPost(ctx, stored.ID, stored)
+	r.postIds = append(r.postIds, stored.ID)
+
+	return stored.ID, nil
+}
+
+func (r *MemoryRepository) DeletePost(ctx context.Context, postId int32) error {
+	defer r.writeLock(ctx)()
+
+	if _, exists := r.posts[postId]; !exists {
+		return ErrWrongPostId
+	}
+
+	for id, comment := range r.comments {
+		if comment.PostID == postId {
+			r.setComment(ctx, id, nil)
+		}
+	}
+
+	r.setPost(ctx, postId, nil)
+
+	for i, id := range r.postIds {
+		if id == postId {
+			postIds := r.postIds
+			r.logUndo(ctx, func() { r.postIds = postIds })
+
+			r.postIds = append(append(make([]int32, 0, len(postIds)), postIds[:i]...), postIds[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) GetPostById(ctx context.Context, id int32) (*model.Post, error) {
+	defer r.readLock(ctx)()
+
+	post, exists := r.posts[id]
+	if !exists {
+		return nil, ErrWrongPostId
+	}
+
+	return copyPost(post), nil
+}
+
+func (r *MemoryRepository) GetPostsByIds(ctx context.Context, ids []int32) ([]*model.Post, error) {
+	defer r.readLock(ctx)()
+
+	posts := make([]*model.Post, 0, len(ids))
+	for _, id := range ids {
+		if post, exists := r.posts[id]; exists {
+			posts = append(posts, copyPost(post))
+		}
+	}
+
+	return posts, nil
+}
+
+func (r *MemoryRepository) GetPostByIdWithComments(ctx context.Context, id int32) (*model.Post, error) {
+	defer r.readLock(ctx)()
+
+	stored, exists := r.posts[id]
+	if !exists {
+		return nil, ErrWrongPostId
+	}
+
+	post := copyPost(stored)
+	post.Comments = buildCommentsTree(r.postComments(id))
+
+	return post, nil
+}
+
+func (r *MemoryRepository) CreateComment(ctx context.Context, comment *model.Comment) (int32, error) {
+	defer r.writeLock(ctx)()
+
+	if _, exists := r.posts[comment.PostID]; !exists {
+		return 0, ErrWrongPostId
+	}
+
+	var depth int32
+
+	if parentId := pointer.Deref(comment.ParentID, 0); parentId != 0 {
+		parent, exists := r.comments[parentId]
+		if !exists {
+			return 0, ErrWrongCommentId
+		}
+
+		depth = parent.Depth + 1
+	}
+
+	lastCommentId := r.lastCommentId
+	r.logUndo(ctx, func() { r.lastCommentId = lastCommentId })
+
+	r.lastCommentId++
+
+	stored := copyComment(comment)
+	stored.ID = r.lastCommentId
+	stored.Depth = depth
+
+	r.setComment(ctx, stored.ID, stored)
+
+	post := copyPost(r.posts[stored.PostID])
+	post.CommentCount++
+	if stored.CreatedAt.After(post.LastActivityAt) {
+		post.LastActivityAt = stored.CreatedAt
+	}
+	r.setPost(ctx, post.ID, post)
+
+	return stored.ID, nil
+}
+
+func (r *MemoryRepository) GetCommentById(ctx context.Context, commentId int32) (*model.Comment, error) {
+	defer r.readLock(ctx)()
+
+	comment, exists := r.comments[commentId]
+	if !exists {
+		return nil, ErrWrongCommentId
+	}
+
+	return copyComment(comment), nil
+}
+
+func (r *MemoryRepository) GetCommentsByIds(ctx context.Context, ids []int32) ([]*model.Comment, error) {
+	defer r.readLock(ctx)()
+
+	comments := make([]*model.Comment, 0, len(ids))
+	for _, id := range ids {
+		if comment, exists := r.comments[id]; exists {
+			comments = append(comments, copyComment(comment))
+		}
+	}
+
+	return comments, nil
+}
+
+func (r *MemoryRepository) GetCommentsAfter(ctx context.Context, postId int32, afterId int32) ([]*model.Comment, error) {
+	defer r.readLock(ctx)()
+
+	comments := make([]*model.Comment, 0)
+	for _, comment := range r.comments {
+		if comment.PostID == postId && comment.ID > afterId {
+			comments = append(comments, copyComment(comment))
+		}
+	}
+
+	sort.Slice(comments, func(i, j int) bool {
+		return comments[i].ID < comments[j].ID
+	})
+
+	return comments, nil
+}
+
+func (r *MemoryRepository) GetCommentsByPostId(ctx context.Context, postId int32, maxDepth int32, limit, offset int32) ([]*model.Comment, error) {
+	defer r.readLock(ctx)()
+
+	var comments []*model.Comment
+
+	for _, comment := range r.postComments(postId) {
+		if maxDepth == UnlimitedDepth || comment.Depth <= maxDepth {
+			comments = append(comments, comment)
+		}
+	}
+
+	comments = paginate(comments, limit, offset)
+	if len(comments) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return buildCommentsTree(comments), nil
+}
+
+func (r *MemoryRepository) GetCommentThread(ctx context.Context, commentId int32, maxDepth int32) (*model.Comment, error) {
+	defer r.readLock(ctx)()
+
+	root, exists := r.comments[commentId]
+	if !exists {
+		return nil, ErrWrongCommentId
+	}
+
+	var thread []*model.Comment
+
+	for _, comment := range r.postComments(root.PostID) {
+		if maxDepth != UnlimitedDepth && comment.Depth > root.Depth+maxDepth {
+			continue
+		}
+
+		if r.isDescendant(comment, commentId) {
+			thread = append(thread, comment)
+		}
+	}
+
+	for _, comment := range buildCommentsTree(thread) {
+		if comment.ID == commentId {
+			return comment, nil
+		}
+	}
+
+	return nil, ErrWrongCommentId
+}
+
+func (r *MemoryRepository) DeleteComment(ctx context.Context, commentId int32) error {
+	defer r.writeLock(ctx)()
+
+	deleted, exists := r.comments[commentId]
+	if !exists {
+		return ErrWrongCommentId
+	}
+
+	for _, comment := range r.comments {
+		if pointer.Deref(comment.ParentID, 0) == commentId {
+			return ErrCommentHasReplies
+		}
+	}
+
+	r.setComment(ctx, commentId, nil)
+	r.updatePostActivity(ctx, deleted.PostID, -1)
+
+	for id, comment := range r.comments {
+		if pointer.Deref(comment.QuotedID, 0) == commentId {
+			unquoted := copyComment(comment)
+			unquoted.QuotedID = nil
+			r.setComment(ctx, id, unquoted)
+		}
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) GetIdempotencyRecord(ctx context.Context, scope string, key string, now time.Time) (*IdempotencyRecord, error) {
+	defer r.readLock(ctx)()
+
+	record, ok := r.idempotencyRecords[idempotencyKey{scope, key}]
+	if !ok || !record.ExpiresAt.After(now) {
+		return nil, ErrNotFound
+	}
+
+	copied := *record
+
+	return &copied, nil
+}
+
+func (r *MemoryRepository) SaveIdempotencyRecord(ctx context.Context, record *IdempotencyRecord) error {
+	defer r.writeLock(ctx)()
+
+	key := idempotencyKey{record.Scope, record.Key}
+	if stored, ok := r.idempotencyRecords[key]; ok && stored.ExpiresAt.After(record.CreatedAt) {
+		return ErrIdempotencyKeyInUse
+	}
+
+	copied := *record
+	r.setIdempotencyRecord(ctx, key, &copied)
+
+	return nil
+}
+
+func (r *MemoryRepository) DeleteExpiredIdempotencyRecords(ctx context.Context, now time.Time) (int64, error) {
+	defer r.writeLock(ctx)()
+
+	var deleted int64
+
+	for key, record := range r.idempotencyRecords {
+		if !record.ExpiresAt.After(now) {
+			r.setIdempotencyRecord(ctx, key, nil)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// WithinTransaction holds the exclusive lock while fn runs and undoes its changes if it fails.
+// The isolation level is ignored as transactions are fully serialized.
+func (r *MemoryRepository) WithinTransaction(ctx context.Context, isolation sql.IsolationLevel, fn func(ctx context.Context) error) error {
+	if ctx.Value(memoryTxKey{}) != nil {
+		return fn(ctx)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	committed := false
+	defer func() {
+		if !committed {
+			r.rollback(0)
+		}
+		r.undo = nil
+	}()
+
+	if err := fn(context.WithValue(ctx, memoryTxKey{}, struct{}{})); err != nil {
+		return err
+	}
+
+	committed = true
+
+	return nil
+}
+
+// WithinReadOnlyTransaction holds the shared lock while fn runs, so fn sees a consistent state.
+func (r *MemoryRepository) WithinReadOnlyTransaction(ctx context.Context, isolation sql.IsolationLevel, fn func(ctx context.Context) error) error {
+	if ctx.Value(memoryTxKey{}) != nil {
+		return fn(ctx)
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return fn(context.WithValue(ctx, memoryTxKey{}, struct{}{}))
+}
+
+// WithinSavepoint undoes the changes of fn if it fails, without failing the whole transaction.
+func (r *MemoryRepository) WithinSavepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(memoryTxKey{}) == nil {
+		return r.WithinTransaction(ctx, sql.LevelDefault, fn)
+	}
+
+	savepoint := len(r.undo)
+
+	if err := fn(ctx); err != nil {
+		r.rollback(savepoint)
+		return err
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) readLock(ctx context.Context) func() {
+	if ctx.Value(memoryTxKey{}) != nil {
+		return func() {}
+	}
+
+	r.lock.RLock()
+	return r.lock.RUnlock
+}
+
+func (r *MemoryRepository) writeLock(ctx context.Context) func() {
+	if ctx.Value(memoryTxKey{}) != nil {
+		return func() {}
+	}
+
+	r.lock.Lock()
+	return r.lock.Unlock
+}
+
+// logUndo records how to revert a change made inside a transaction, changes outside of one are final.
+func (r *MemoryRepository) logUndo(ctx context.Context, undo func()) {
+	if ctx.Value(memoryTxKey{}) != nil {
+		r.undo = append(r.undo, undo)
+	}
+}
+
+// rollback reverts the changes logged after the savepoint.
+func (r *MemoryRepository) rollback(savepoint int) {
+	for i := len(r.undo) - 1; i >= savepoint; i-- {
+		r.undo[i]()
+	}
+	r.undo = r.undo[:savepoint]
+}
+
+// setPost stores the post, or deletes it if post is nil.
+func (r *MemoryRepository) setPost(ctx context.Context, id int32, post *model.Post) {
+	previous, existed := r.posts[id]
+	r.logUndo(ctx, func() {
+		if existed {
+			r.posts[id] = previous
+		} else {
+			delete(r.posts, id)
+		}
+	})
+
+	if post == nil {
+		delete(r.posts, id)
+		return
+	}
+	r.posts[id] = post
+}
+
+// setComment stores the comment, or deletes it if comment is nil.
+func (r *MemoryRepository) setComment(ctx context.Context, id int32, comment *model.Comment) {
+	previous, existed := r.comments[id]
+	r.logUndo(ctx, func() {
+		if existed {
+			r.comments[id] = previous
+		} else {
+			delete(r.comments, id)
+		}
+	})
+
+	if comment == nil {
+		delete(r.comments, id)
+		return
+	}
+	r.comments[id] = comment
+}
+
+// setIdempotencyRecord stores the record, or deletes it if record is nil.
+func (r *MemoryRepository) setIdempotencyRecord(ctx context.Context, key idempotencyKey, record *IdempotencyRecord) {
+	previous, existed := r.idempotencyRecords[key]
+	r.logUndo(ctx, func() {
+		if existed {
+			r.idempotencyRecords[key] = previous
+		} else {
+			delete(r.idempotencyRecords, key)
+		}
+	})
+
+	if record == nil {
+		delete(r.idempotencyRecords, key)
+		return
+	}
+	r.idempotencyRecords[key] = record
+}
+
+// updatePostActivity replaces the stored post instead of changing it, so the undo log keeps the previous one.
+func (r *MemoryRepository) updatePostActivity(ctx context.Context, postId int32, delta int32) {
+	post := copyPost(r.posts[postId])
+	post.CommentCount += delta
+	post.LastActivityAt = post.CreatedAt
+
+	for _, comment := range r.comments {
+		if comment.PostID == postId && comment.CreatedAt.After(post.LastActivityAt) {
+			post.LastActivityAt = comment.CreatedAt
+		}
+	}
+
+	r.setPost(ctx, postId, post)
+}
+
+// isDescendant reports whether the comment is the ancestor itself or one of its replies at any depth.
+func (r *MemoryRepository) isDescendant(comment *model.Comment, ancestorId int32) bool {
+	for {
+		if comment.ID == ancestorId {
+			return true
+		}
+
+		parent, exists := r.comments[pointer.Deref(comment.ParentID, 0)]
+		if !exists {
+			return false
+		}
+
+		comment = parent
+	}
+}
+
+// postComments returns copies of the post comments ordered the same way as the sql storage orders them.
+func (r *MemoryRepository) postComments(postId int32) []*model.Comment {
+	comments := make([]*model.Comment, 0)
+
+	for _, comment := range r.comments {
+		if comment.PostID == postId {
+			comments = append(comments, copyComment(comment))
+		}
+	}
+
+	sort.Slice(comments, func(i, j int) bool {
+		if !comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+		}
+		return comments[i].ID < comments[j].ID
+	})
+
+	return comments
+}
+
+func paginate[T any](items []T, limit, offset int32) []T {
+	if offset < 0 {
+		offset = 0
+	}
+
+	if int(offset) >= len(items) {
+		return nil
+	}
+
+	items = items[offset:]
+
+	if limit >= 0 && int(limit) < len(items) {
+		items = items[:limit]
+	}
+
+	return items
+}
+
+func copyPost(post *model.Post) *model.Post {
+	copied := *post
+	copied.Comments = nil
+
+	return &copied
+}
+
+func copyComment(comment *model.Comment) *model.Comment {
+	copied := *comment
+	copied.Replies = nil
+
+	if comment.ParentID != nil {
+		parentId := *comment.ParentID
+		copied.ParentID = &parentId
+	}
+
+	if comment.QuotedID != nil {
+		quotedId := *comment.QuotedID
+		copied.QuotedID = &quotedId
+	}
+
+	return &copied
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"ozon-tesk-task/internal/database"
 	"ozon-tesk-task/internal/transport/graph/model"
 	"ozon-tesk-task/pkg/pointer"
@@ -11,6 +12,16 @@ import (
 	sq "github.com/Masterminds/squirrel"
 )
 
+const (
+	maxTxAttempts = 3
+	savepointName = "item"
+)
+
+// UnlimitedDepth disables the depth limit of comment tree fetches.
+const UnlimitedDepth int32 = -1
+
+type txKey struct{}
+
 type Repository struct {
 	db *database.Database
 }
@@ -19,14 +30,118 @@ func New(db *database.Database) *Repository {
 	return &Repository{db: db}
 }
 
+// WithinTransaction runs fn in a transaction with the given isolation level. Repository calls made
+// with the context passed to fn use that transaction, nested calls join the outer transaction.
+func (r *Repository) WithinTransaction(ctx context.Context, isolation sql.IsolationLevel, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	var err error
+
+	begin := func() (*sql.Tx, error) {
+		return r.db.Writer(ctx).BeginTx(ctx, &sql.TxOptions{Isolation: isolation})
+	}
+
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = r.runTransaction(ctx, begin, fn)
+		if !isSerializationFailure(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// WithinReadOnlyTransaction runs fn in a read-only transaction with the given isolation level, which
+// is started on a replica if there is one. Inside another transaction fn joins the outer transaction.
+func (r *Repository) WithinReadOnlyTransaction(ctx context.Context, isolation sql.IsolationLevel, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	return r.runTransaction(ctx, func() (*sql.Tx, error) {
+		return r.db.BeginReadOnly(ctx, isolation)
+	}, fn)
+}
+
+func (r *Repository) runTransaction(ctx context.Context, begin func() (*sql.Tx, error), fn func(ctx context.Context) error) (err error) {
+	tx, err := begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// WithinSavepoint runs fn in a savepoint of the transaction of the context, so a failure of fn only rolls
+// back its own changes and the transaction can go on. Without a transaction it starts one.
+func (r *Repository) WithinSavepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	if !ok {
+		return r.WithinTransaction(ctx, sql.LevelDefault, fn)
+	}
+
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+savepointName); err != nil {
+		return err
+	}
+
+	if err := fn(ctx); err != nil {
+		if _, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepointName); rollbackErr != nil {
+			return rollbackErr
+		}
+		if _, releaseErr := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepointName); releaseErr != nil {
+			return releaseErr
+		}
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepointName)
+	return err
+}
+
+// runner returns the transaction of the context or the primary database.
+func (r *Repository) runner(ctx context.Context) sq.BaseRunner {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+
+	return r.db.Writer(ctx)
+}
+
+// reader returns the transaction of the context or a database to read from.
+func (r *Repository) reader(ctx context.Context) sq.BaseRunner {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+
+	return r.db.Reader(ctx)
+}
+
 func (r *Repository) ListPosts(ctx context.Context, limit, offset int32) ([]*model.Post, error) {
-	rows, err := sq.Select("id", "user_id", "title", "content", "comments_allowed", "created_at").
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
+	rows, err := sq.Select("id", "user_id", "title", "content", "comments_allowed", "created_at", "comment_count", "last_activity_at").
 		From("posts").
+		OrderBy("id").
 		Limit(uint64(limit)).
 		Offset(uint64(offset)).
 		PlaceholderFormat(sq.Dollar).
-		RunWith(r.db.DB).
-		Query()
+		RunWith(r.reader(ctx)).
+		QueryContext(ctx)
 
 	if err != nil {
 		return nil, err
@@ -38,14 +153,14 @@ func (r *Repository) ListPosts(ctx context.Context, limit, offset int32) ([]*mod
 	for rows.Next() {
 		var post model.Post
 
-		if err := rows.Scan(&post.ID, &post.Author, &post.Title, &post.Content, &post.AllowComments, &post.CreatedAt); err != nil {
+		if err := rows.Scan(&post.ID, &post.Author, &post.Title, &post.Content, &post.AllowComments, &post.CreatedAt, &post.CommentCount, &post.LastActivityAt); err != nil {
 			return nil, err
 		}
 
 		posts = append(posts, &post)
 	}
 
-	if rows.Err() != nil {
+	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
@@ -57,15 +172,22 @@ func (r *Repository) ListPosts(ctx context.Context, limit, offset int32) ([]*mod
 }
 
 func (r *Repository) ListPostsWithComments(ctx context.Context, limit, offset int32) ([]*model.Post, error) {
-	rows, err := sq.Select("p.id", "p.user_id", "p.title", "p.content", "p.comments_allowed", "p.created_at", "c.id", "c.post_id", "c.user_id", "c.parent_comment_id", "c.content", "c.created_at").
-		From("posts p").
-		LeftJoin("comments c on p.id = c.post_id").
-		OrderBy("p.created_at, c.created_at").
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
+	page := sq.Select("id", "user_id", "title", "content", "comments_allowed", "created_at", "comment_count", "last_activity_at").
+		From("posts").
+		OrderBy("id").
 		Limit(uint64(limit)).
-		Offset(uint64(offset)).
+		Offset(uint64(offset))
+
+	rows, err := sq.Select("p.id", "p.user_id", "p.title", "p.content", "p.comments_allowed", "p.created_at", "p.comment_count", "p.last_activity_at", "c.id", "c.post_id", "c.user_id", "c.parent_comment_id", "c.quoted_comment_id", "c.depth", "c.content", "c.created_at").
+		FromSelect(page, "p").
+		LeftJoin("comments c on p.id = c.post_id").
+		OrderBy("p.id, c.created_at, c.id").
 		PlaceholderFormat(sq.Dollar).
-		RunWith(r.db.DB).
-		Query()
+		RunWith(r.reader(ctx)).
+		QueryContext(ctx)
 
 	if err != nil {
 		return nil, err
@@ -86,11 +208,13 @@ func (r *Repository) ListPostsWithComments(ctx context.Context, limit, offset in
 			postId    sql.NullInt32
 			author    sql.NullInt32
 			parentId  sql.NullInt32
+			quotedId  sql.NullInt32
+			depth     sql.NullInt32
 			content   sql.NullString
-			createdAt sql.NullString
+			createdAt sql.NullTime
 		)
 
-		if err = rows.Scan(&post.ID, &post.Author, &post.Title, &post.Content, &post.AllowComments, &post.CreatedAt, &id, &postId, &author, &parentId, &content, &createdAt); err != nil {
+		if err = rows.Scan(&post.ID, &post.Author, &post.Title, &post.Content, &post.AllowComments, &post.CreatedAt, &post.CommentCount, &post.LastActivityAt, &id, &postId, &author, &parentId, &quotedId, &depth, &content, &createdAt); err != nil {
 			return nil, err
 		}
 
@@ -99,9 +223,11 @@ func (r *Repository) ListPostsWithComments(ctx context.Context, limit, offset in
 				ID:        id.Int32,
 				PostID:    postId.Int32,
 				Author:    author.Int32,
-				ParentID:  &parentId.Int32,
+				ParentID:  nullInt32(parentId),
+				QuotedID:  nullInt32(quotedId),
+				Depth:     depth.Int32,
 				Content:   content.String,
-				CreatedAt: createdAt.String,
+				CreatedAt: createdAt.Time,
 			}
 
 			commentMap[post.ID] = append(commentMap[post.ID], &comment)
@@ -113,12 +239,12 @@ func (r *Repository) ListPostsWithComments(ctx context.Context, limit, offset in
 		}
 	}
 
-	for _, post := range posts {
-		post.Comments = buildCommentsTree(commentMap[post.ID])
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
 
-	if rows.Err() != nil {
-		return nil, err
+	for _, post := range posts {
+		post.Comments = buildCommentsTree(commentMap[post.ID])
 	}
 
 	if len(posts) == 0 {
@@ -129,15 +255,18 @@ func (r *Repository) ListPostsWithComments(ctx context.Context, limit, offset in
 }
 
 func (r *Repository) CreatePost(ctx context.Context, post *model.Post) (int32, error) {
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
 	var id int32
 
 	err := sq.Insert("posts").
-		Columns("user_id", "title", "content", "comments_allowed", "created_at").
-		Values(post.Author, post.Title, post.Content, post.AllowComments, post.CreatedAt).
+		Columns("user_id", "title", "content", "comments_allowed", "created_at", "last_activity_at").
+		Values(post.Author, post.Title, post.Content, post.AllowComments, post.CreatedAt, post.CreatedAt).
 		Suffix("RETURNING id").
 		PlaceholderFormat(sq.Dollar).
-		RunWith(r.db.DB).
-		QueryRow().
+		RunWith(r.runner(ctx)).
+		QueryRowContext(ctx).
 		Scan(&id)
 
 	if err != nil {
@@ -148,59 +277,54 @@ func (r *Repository) CreatePost(ctx context.Context, post *model.Post) (int32, e
 }
 
 func (r *Repository) DeletePost(ctx context.Context, postId int32) error {
-	tx, err := r.db.DB.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-
-	_, err = sq.Delete("comments").
-		Where(sq.Eq{"post_id": postId}).
-		PlaceholderFormat(sq.Dollar).
-		RunWith(tx).
-		Exec()
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	res, err := sq.Delete("posts").
-		Where(sq.Eq{"id": postId}).
-		PlaceholderFormat(sq.Dollar).
-		RunWith(tx).
-		Exec()
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
+	return r.WithinTransaction(ctx, sql.LevelDefault, func(ctx context.Context) error {
+		_, err := sq.Delete("comments").
+			Where(sq.Eq{"post_id": postId}).
+			PlaceholderFormat(sq.Dollar).
+			RunWith(r.runner(ctx)).
+			ExecContext(ctx)
+		if err != nil {
+			return err
+		}
 
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
+		res, err := sq.Delete("posts").
+			Where(sq.Eq{"id": postId}).
+			PlaceholderFormat(sq.Dollar).
+			RunWith(r.runner(ctx)).
+			ExecContext(ctx)
+		if err != nil {
+			return err
+		}
 
-	if rowsAffected == 0 {
-		tx.Rollback()
-		return ErrWrongPostId
-	}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
 
-	if err = tx.Commit(); err != nil {
-		return err
-	}
+		if rowsAffected == 0 {
+			return ErrWrongPostId
+		}
 
-	return nil
+		return nil
+	})
 }
 
 func (r *Repository) GetPostById(ctx context.Context, id int32) (*model.Post, error) {
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
 	var post model.Post
 
-	err := sq.Select("id", "user_id", "title", "content", "comments_allowed", "created_at").
+	err := sq.Select("id", "user_id", "title", "content", "comments_allowed", "created_at", "comment_count", "last_activity_at").
 		From("posts").
 		Where(sq.Eq{"id": id}).
 		PlaceholderFormat(sq.Dollar).
-		RunWith(r.db.DB).
-		QueryRow().
-		Scan(&post.ID, &post.Author, &post.Title, &post.Content, &post.AllowComments, &post.CreatedAt)
+		RunWith(r.reader(ctx)).
+		QueryRowContext(ctx).
+		Scan(&post.ID, &post.Author, &post.Title, &post.Content, &post.AllowComments, &post.CreatedAt, &post.CommentCount, &post.LastActivityAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -212,15 +336,53 @@ func (r *Repository) GetPostById(ctx context.Context, id int32) (*model.Post, er
 	return &post, nil
 }
 
+// GetPostsByIds returns the existing posts among the ids in no particular order.
+func (r *Repository) GetPostsByIds(ctx context.Context, ids []int32) ([]*model.Post, error) {
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
+	rows, err := sq.Select("id", "user_id", "title", "content", "comments_allowed", "created_at", "comment_count", "last_activity_at").
+		From("posts").
+		Where(sq.Eq{"id": ids}).
+		PlaceholderFormat(sq.Dollar).
+		RunWith(r.reader(ctx)).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := make([]*model.Post, 0, len(ids))
+
+	for rows.Next() {
+		var post model.Post
+
+		if err := rows.Scan(&post.ID, &post.Author, &post.Title, &post.Content, &post.AllowComments, &post.CreatedAt, &post.CommentCount, &post.LastActivityAt); err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, &post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
 func (r *Repository) GetPostByIdWithComments(ctx context.Context, id int32) (*model.Post, error) {
-	rows, err := sq.Select("p.id", "p.user_id", "p.title", "p.content", "p.comments_allowed", "p.created_at", "c.id", "c.post_id", "c.user_id", "c.parent_comment_id", "c.content", "c.created_at").
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
+	rows, err := sq.Select("p.id", "p.user_id", "p.title", "p.content", "p.comments_allowed", "p.created_at", "p.comment_count", "p.last_activity_at", "c.id", "c.post_id", "c.user_id", "c.parent_comment_id", "c.quoted_comment_id", "c.depth", "c.content", "c.created_at").
 		From("posts p").
 		LeftJoin("comments c on p.id = c.post_id").
 		Where(sq.Eq{"p.id": id}).
-		OrderBy("c.created_at").
+		OrderBy("c.created_at, c.id").
 		PlaceholderFormat(sq.Dollar).
-		RunWith(r.db.DB).
-		Query()
+		RunWith(r.reader(ctx)).
+		QueryContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -241,11 +403,13 @@ func (r *Repository) GetPostByIdWithComments(ctx context.Context, id int32) (*mo
 			postId    sql.NullInt32
 			author    sql.NullInt32
 			parentId  sql.NullInt32
+			quotedId  sql.NullInt32
+			depth     sql.NullInt32
 			content   sql.NullString
-			createdAt sql.NullString
+			createdAt sql.NullTime
 		)
 
-		if err = rows.Scan(&post.ID, &post.Author, &post.Title, &post.Content, &post.AllowComments, &post.CreatedAt, &id, &postId, &author, &parentId, &content, &createdAt); err != nil {
+		if err = rows.Scan(&post.ID, &post.Author, &post.Title, &post.Content, &post.AllowComments, &post.CreatedAt, &post.CommentCount, &post.LastActivityAt, &id, &postId, &author, &parentId, &quotedId, &depth, &content, &createdAt); err != nil {
 			return nil, err
 		}
 
@@ -254,9 +418,11 @@ func (r *Repository) GetPostByIdWithComments(ctx context.Context, id int32) (*mo
 				ID:        id.Int32,
 				PostID:    postId.Int32,
 				Author:    author.Int32,
-				ParentID:  &parentId.Int32,
+				ParentID:  nullInt32(parentId),
+				QuotedID:  nullInt32(quotedId),
+				Depth:     depth.Int32,
 				Content:   content.String,
-				CreatedAt: createdAt.String,
+				CreatedAt: createdAt.Time,
 			}
 
 			comments = append(comments, &comment)
@@ -267,16 +433,19 @@ func (r *Repository) GetPostByIdWithComments(ctx context.Context, id int32) (*mo
 		}
 	}
 
-	post.Comments = buildCommentsTree(comments)
-
-	if rows.Err() != nil {
+	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
+	post.Comments = buildCommentsTree(comments)
+
 	return &post, nil
 }
 
 func (r *Repository) CreateComment(ctx context.Context, comment *model.Comment) (int32, error) {
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
 	var id int32
 
 	values := []interface{}{comment.PostID, comment.Author, comment.Content, comment.CreatedAt}
@@ -287,16 +456,74 @@ func (r *Repository) CreateComment(ctx context.Context, comment *model.Comment)
 		values = append(values, *comment.ParentID)
 	}
 
-	err := sq.Insert("comments").
-		Columns(columns...).
-		Values(values...).
-		Suffix("RETURNING id").
-		PlaceholderFormat(sq.Dollar).
-		RunWith(r.db.DB).
-		QueryRow().
-		Scan(&id)
+	if comment.QuotedID != nil {
+		columns = append(columns, "quoted_comment_id")
+		values = append(values, *comment.QuotedID)
+	}
+
+	err := r.WithinTransaction(ctx, sql.LevelDefault, func(ctx context.Context) error {
+		var (
+			parentPath string
+			depth      int32
+		)
+
+		if comment.ParentID != nil {
+			err := sq.Select("path", "depth + 1").
+				From("comments").
+				Where(sq.Eq{"id": *comment.ParentID}).
+				PlaceholderFormat(sq.Dollar).
+				RunWith(r.runner(ctx)).
+				QueryRowContext(ctx).
+				Scan(&parentPath, &depth)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return ErrWrongCommentId
+				}
+				return err
+			}
+		}
+
+		err := sq.Insert("comments").
+			Columns(columns...).
+			Values(values...).
+			Suffix("RETURNING id").
+			PlaceholderFormat(sq.Dollar).
+			RunWith(r.runner(ctx)).
+			QueryRowContext(ctx).
+			Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		_, err = sq.Update("comments").
+			Set("path", fmt.Sprintf("%s%d/", parentPath, id)).
+			Set("depth", depth).
+			Where(sq.Eq{"id": id}).
+			PlaceholderFormat(sq.Dollar).
+			RunWith(r.runner(ctx)).
+			ExecContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		return r.updatePostActivity(ctx, comment.PostID, 1)
+	})
 
 	if err != nil {
+		if isForeignKeyViolation(err) {
+			switch foreignKeyConstraint(err) {
+			case postgresCommentPostForeignKey:
+				return 0, ErrWrongPostId
+			case postgresCommentParentForeignKey:
+				return 0, ErrWrongCommentId
+			}
+
+			if _, postErr := r.GetPostById(ctx, comment.PostID); errors.Is(postErr, ErrWrongPostId) {
+				return 0, ErrWrongPostId
+			}
+			return 0, ErrWrongCommentId
+		}
+
 		return 0, err
 	}
 
@@ -304,15 +531,18 @@ func (r *Repository) CreateComment(ctx context.Context, comment *model.Comment)
 }
 
 func (r *Repository) GetCommentById(ctx context.Context, commentId int32) (*model.Comment, error) {
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
 	var comment model.Comment
 
-	err := sq.Select("id", "post_id", "user_id", "parent_comment_id", "content", "created_at").
+	err := sq.Select("id", "post_id", "user_id", "parent_comment_id", "quoted_comment_id", "depth", "content", "created_at").
 		From("comments").
 		Where(sq.Eq{"id": commentId}).
 		PlaceholderFormat(sq.Dollar).
-		RunWith(r.db.DB).
-		QueryRow().
-		Scan(&comment.ID, &comment.PostID, &comment.Author, &comment.ParentID, &comment.Content, &comment.CreatedAt)
+		RunWith(r.reader(ctx)).
+		QueryRowContext(ctx).
+		Scan(&comment.ID, &comment.PostID, &comment.Author, &comment.ParentID, &comment.QuotedID, &comment.Depth, &comment.Content, &comment.CreatedAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -325,16 +555,97 @@ func (r *Repository) GetCommentById(ctx context.Context, commentId int32) (*mode
 	return &comment, nil
 }
 
-func (r *Repository) GetCommentsByPostId(ctx context.Context, postId int32, limit, offset int32) ([]*model.Comment, error) {
-	rows, err := sq.Select("id", "post_id", "user_id", "parent_comment_id", "content", "created_at").
+// GetCommentsByIds returns the existing comments among the ids in no particular order.
+func (r *Repository) GetCommentsByIds(ctx context.Context, ids []int32) ([]*model.Comment, error) {
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
+	rows, err := sq.Select("id", "post_id", "user_id", "parent_comment_id", "quoted_comment_id", "depth", "content", "created_at").
+		From("comments").
+		Where(sq.Eq{"id": ids}).
+		PlaceholderFormat(sq.Dollar).
+		RunWith(r.reader(ctx)).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := make([]*model.Comment, 0, len(ids))
+
+	for rows.Next() {
+		var comment model.Comment
+
+		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.Author, &comment.ParentID, &comment.QuotedID, &comment.Depth, &comment.Content, &comment.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		comments = append(comments, &comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// GetCommentsAfter returns the comments of the post with an id greater than afterId in the order of their ids.
+func (r *Repository) GetCommentsAfter(ctx context.Context, postId int32, afterId int32) ([]*model.Comment, error) {
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
+	rows, err := sq.Select("id", "post_id", "user_id", "parent_comment_id", "quoted_comment_id", "depth", "content", "created_at").
 		From("comments").
 		Where(sq.Eq{"post_id": postId}).
-		OrderBy("created_at").
+		Where(sq.Gt{"id": afterId}).
+		OrderBy("id").
+		PlaceholderFormat(sq.Dollar).
+		RunWith(r.reader(ctx)).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := make([]*model.Comment, 0)
+
+	for rows.Next() {
+		var comment model.Comment
+
+		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.Author, &comment.ParentID, &comment.QuotedID, &comment.Depth, &comment.Content, &comment.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		comments = append(comments, &comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+func (r *Repository) GetCommentsByPostId(ctx context.Context, postId int32, maxDepth int32, limit, offset int32) ([]*model.Comment, error) {
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
+	query := sq.Select("id", "post_id", "user_id", "parent_comment_id", "quoted_comment_id", "depth", "content", "created_at").
+		From("comments").
+		Where(sq.Eq{"post_id": postId})
+
+	if maxDepth != UnlimitedDepth {
+		query = query.Where(sq.LtOrEq{"depth": maxDepth})
+	}
+
+	rows, err := query.
+		OrderBy("created_at, id").
 		Limit(uint64(limit)).
 		Offset(uint64(offset)).
 		PlaceholderFormat(sq.Dollar).
-		RunWith(r.db.DB).
-		Query()
+		RunWith(r.reader(ctx)).
+		QueryContext(ctx)
 
 	if err != nil {
 		return nil, err
@@ -354,11 +665,13 @@ func (r *Repository) GetCommentsByPostId(ctx context.Context, postId int32, limi
 			postId    sql.NullInt32
 			author    sql.NullInt32
 			parentId  sql.NullInt32
+			quotedId  sql.NullInt32
+			depth     sql.NullInt32
 			content   sql.NullString
-			createdAt sql.NullString
+			createdAt sql.NullTime
 		)
 
-		if err = rows.Scan(&id, &postId, &author, &parentId, &content, &createdAt); err != nil {
+		if err = rows.Scan(&id, &postId, &author, &parentId, &quotedId, &depth, &content, &createdAt); err != nil {
 			return nil, err
 		}
 
@@ -367,9 +680,11 @@ func (r *Repository) GetCommentsByPostId(ctx context.Context, postId int32, limi
 				ID:        id.Int32,
 				PostID:    postId.Int32,
 				Author:    author.Int32,
-				ParentID:  &parentId.Int32,
+				ParentID:  nullInt32(parentId),
+				QuotedID:  nullInt32(quotedId),
+				Depth:     depth.Int32,
 				Content:   content.String,
-				CreatedAt: createdAt.String,
+				CreatedAt: createdAt.Time,
 			}
 
 			comments = append(comments, &comment)
@@ -380,7 +695,7 @@ func (r *Repository) GetCommentsByPostId(ctx context.Context, postId int32, limi
 		}
 	}
 
-	if rows.Err() != nil {
+	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
@@ -390,26 +705,163 @@ func (r *Repository) GetCommentsByPostId(ctx context.Context, postId int32, limi
 
 }
 
-func (r *Repository) DeleteComment(ctx context.Context, commentId int32) error {
-	res, err := sq.Delete("comments").
+func (r *Repository) GetCommentThread(ctx context.Context, commentId int32, maxDepth int32) (*model.Comment, error) {
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
+	var (
+		path  string
+		depth int32
+	)
+
+	err := sq.Select("path", "depth").
+		From("comments").
 		Where(sq.Eq{"id": commentId}).
 		PlaceholderFormat(sq.Dollar).
-		RunWith(r.db.DB).
-		Exec()
+		RunWith(r.reader(ctx)).
+		QueryRowContext(ctx).
+		Scan(&path, &depth)
 	if err != nil {
-		return err
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWrongCommentId
+		}
+		return nil, err
+	}
+
+	query := sq.Select("id", "post_id", "user_id", "parent_comment_id", "quoted_comment_id", "depth", "content", "created_at").
+		From("comments").
+		Where(sq.Like{"path": path + "%"})
+
+	if maxDepth != UnlimitedDepth {
+		query = query.Where(sq.LtOrEq{"depth": depth + maxDepth})
 	}
 
-	rowsAffected, err := res.RowsAffected()
+	rows, err := query.
+		OrderBy("created_at, id").
+		PlaceholderFormat(sq.Dollar).
+		RunWith(r.reader(ctx)).
+		QueryContext(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*model.Comment
+
+	for rows.Next() {
+		var (
+			comment  model.Comment
+			parentId sql.NullInt32
+			quotedId sql.NullInt32
+		)
+
+		if err = rows.Scan(&comment.ID, &comment.PostID, &comment.Author, &parentId, &quotedId, &comment.Depth, &comment.Content, &comment.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		comment.ParentID = nullInt32(parentId)
+		comment.QuotedID = nullInt32(quotedId)
+
+		comments = append(comments, &comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, comment := range buildCommentsTree(comments) {
+		if comment.ID == commentId {
+			return comment, nil
+		}
+	}
+
+	return nil, ErrWrongCommentId
+}
+
+func (r *Repository) DeleteComment(ctx context.Context, commentId int32) error {
+	ctx, cancel := r.db.WithStatementTimeout(ctx)
+	defer cancel()
+
+	return r.WithinTransaction(ctx, sql.LevelDefault, func(ctx context.Context) error {
+		var replies int
+
+		err := sq.Select("count(*)").
+			From("comments").
+			Where(sq.Eq{"parent_comment_id": commentId}).
+			PlaceholderFormat(sq.Dollar).
+			RunWith(r.runner(ctx)).
+			QueryRowContext(ctx).
+			Scan(&replies)
+		if err != nil {
+			return err
+		}
+
+		if replies > 0 {
+			return ErrCommentHasReplies
+		}
+
+		var postId int32
+
+		err = sq.Delete("comments").
+			Where(sq.Eq{"id": commentId}).
+			Suffix("RETURNING post_id").
+			PlaceholderFormat(sq.Dollar).
+			RunWith(r.runner(ctx)).
+			QueryRowContext(ctx).
+			Scan(&postId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrWrongCommentId
+			}
+			return err
+		}
+
+		return r.updatePostActivity(ctx, postId, -1)
+	})
+}
+
+// RepairPostActivity recomputes the comment counters of the posts and returns the number of fixed posts.
+func (r *Repository) RepairPostActivity(ctx context.Context) (int64, error) {
+	commentCount := "(SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id)"
+	lastActivity := "COALESCE((SELECT MAX(created_at) FROM comments WHERE comments.post_id = posts.id), created_at)"
+
+	res, err := sq.Update("posts").
+		Set("comment_count", sq.Expr(commentCount)).
+		Set("last_activity_at", sq.Expr(lastActivity)).
+		Where(sq.Or{
+			sq.Expr("comment_count <> " + commentCount),
+			sq.Expr("last_activity_at IS DISTINCT FROM " + lastActivity),
+		}).
+		PlaceholderFormat(sq.Dollar).
+		RunWith(r.runner(ctx)).
+		ExecContext(ctx)
+	if err != nil {
+		return 0, err
 	}
 
-	if rowsAffected == 0 {
-		return ErrWrongCommentId
+	return res.RowsAffected()
+}
+
+// updatePostActivity shifts the comment counter of the post by delta and moves its last activity
+// to the latest comment, or to the post creation if there are no comments left.
+func (r *Repository) updatePostActivity(ctx context.Context, postId int32, delta int) error {
+	_, err := sq.Update("posts").
+		Set("comment_count", sq.Expr("comment_count + ?", delta)).
+		Set("last_activity_at", sq.Expr("COALESCE((SELECT MAX(created_at) FROM comments WHERE post_id = ?), created_at)", postId)).
+		Where(sq.Eq{"id": postId}).
+		PlaceholderFormat(sq.Dollar).
+		RunWith(r.runner(ctx)).
+		ExecContext(ctx)
+
+	return err
+}
+
+func nullInt32(v sql.NullInt32) *int32 {
+	if !v.Valid {
+		return nil
 	}
 
-	return nil
+	return &v.Int32
 }
 
 func buildCommentsTree(comments []*model.Comment) []*model.Comment {
@@ -423,9 +875,10 @@ func buildCommentsTree(comments []*model.Comment) []*model.Comment {
 	for _, comment := range comments {
 		if pointer.Deref(comment.ParentID, 0) == 0 {
 			parentComments = append(parentComments, comment)
-		} else {
-			parent := commentMap[*comment.ParentID]
+		} else if parent, ok := commentMap[*comment.ParentID]; ok {
 			parent.Replies = append(parent.Replies, comment)
+		} else {
+			parentComments = append(parentComments, comment)
 		}
 	}
 
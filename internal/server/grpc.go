@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"ozon-tesk-task/internal/config"
+	"ozon-tesk-task/pkg/logger"
+	"time"
+)
+
+type grpcServer interface {
+	Serve(listener net.Listener) error
+	GracefulStop()
+	Stop()
+}
+
+type GRPCServer struct {
+	grpcServer grpcServer
+	addr       string
+}
+
+func NewGRPCServer(cfg *config.Config, grpcServer grpcServer) *GRPCServer {
+	return &GRPCServer{
+		grpcServer: grpcServer,
+		addr:       ":" + cfg.GRPCPort,
+	}
+}
+
+func (s *GRPCServer) Run(ctx context.Context) error {
+	logs := logger.GetLoggerFromCtx(ctx)
+	logs.Info(ctx, fmt.Sprintf("Starting gRPC server on %s", s.addr))
+
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	return s.grpcServer.Serve(listener)
+}
+
+// Stop waits for running calls to finish, streams are cancelled after the shutdown timeout.
+func (s *GRPCServer) Stop() {
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		s.grpcServer.Stop()
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/internal/transport/graph/model"
+)
+
+// DeletePosts deletes the posts in one transaction. The returned errors match the ids, a failed item
+// does not affect the others.
+func (s *Service) DeletePosts(ctx context.Context, ids []int32) ([]error, error) {
+	return s.runBatch(ctx, len(ids), func(ctx context.Context, i int) error {
+		return s.repo.DeletePost(ctx, ids[i])
+	})
+}
+
+// DeleteComments deletes the comments in one transaction in the given order, so replies have to go
+// before their parents. The returned errors match the ids, a failed item does not affect the others.
+func (s *Service) DeleteComments(ctx context.Context, ids []int32) ([]error, error) {
+	return s.runBatch(ctx, len(ids), func(ctx context.Context, i int) error {
+		return s.repo.DeleteComment(ctx, ids[i])
+	})
+}
+
+// CreateComments creates the comments in one transaction, each of them once per author and idempotency key.
+// The returned results match the comments, a failed item does not affect the others.
+func (s *Service) CreateComments(ctx context.Context, comments []*model.Comment, idempotencyKeys []string) ([]*model.CommentResult, error) {
+	results := make([]*model.CommentResult, len(comments))
+
+	errs, err := s.runBatch(ctx, len(comments), func(ctx context.Context, i int) error {
+		comment, replayed, err := s.CreateComment(ctx, comments[i], idempotencyKeys[i])
+		results[i] = &model.CommentResult{Comment: comment, Replayed: replayed}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, err := range errs {
+		if err != nil {
+			results[i] = &model.CommentResult{Err: err}
+		}
+	}
+
+	return results, nil
+}
+
+// runBatch runs the items in one transaction, each in its own savepoint. Domain errors are reported
+// per item, any other error fails the whole batch.
+func (s *Service) runBatch(ctx context.Context, size int, item func(ctx context.Context, i int) error) ([]error, error) {
+	if s.maxBatchSize > 0 && size > s.maxBatchSize {
+		return nil, repository.ErrBatchTooLarge
+	}
+
+	var errs []error
+
+	err := s.repo.WithinTransaction(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		errs = make([]error, size)
+
+		for i := 0; i < size; i++ {
+			err := s.repo.WithinSavepoint(ctx, func(ctx context.Context) error {
+				return item(ctx, i)
+			})
+
+			var domainErr *repository.Error
+			if err != nil && !errors.As(err, &domainErr) {
+				return err
+			}
+
+			errs[i] = err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return errs, nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"ozon-tesk-task/internal/auth"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/pkg/logger"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// runIdempotent runs create once per client and idempotency key. Repeated requests get the stored result of
+// the first one instead, a key sent with a different request is rejected. The client is the client scope of the
+// request, a request without one can't be told apart from the requests of other clients and runs without the key.
+func runIdempotent[T any](ctx context.Context, s *Service, key string, operation string, request any, create func(ctx context.Context) (T, error)) (result T, replayed bool, err error) {
+	scope := auth.ClientScope(ctx)
+	if key == "" || scope == "" || s.idempotencyTTL <= 0 {
+		result, err = create(ctx)
+		return result, false, err
+	}
+
+	if utf8.RuneCountInString(key) > maxIdempotencyKeyLength {
+		return result, false, repository.ErrInvalidIdempotencyKey
+	}
+
+	hash, err := requestHash(operation, request)
+	if err != nil {
+		return result, false, err
+	}
+
+	err = s.repo.WithinTransaction(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		now := s.timestamp()
+		replayed = false
+
+		record, err := s.repo.GetIdempotencyRecord(ctx, scope, key, now)
+		if err == nil {
+			if record.RequestHash != hash {
+				return repository.ErrIdempotencyKeyReused
+			}
+
+			replayed = true
+
+			return json.Unmarshal(record.Response, &result)
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		if result, err = create(ctx); err != nil {
+			return err
+		}
+
+		response, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+
+		return s.repo.SaveIdempotencyRecord(ctx, &repository.IdempotencyRecord{
+			Scope:       scope,
+			Key:         key,
+			RequestHash: hash,
+			Response:    response,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(s.idempotencyTTL),
+		})
+	})
+	if err != nil {
+		var zero T
+		return zero, false, err
+	}
+
+	return result, replayed, nil
+}
+
+func requestHash(operation string, request any) (string, error) {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(append([]byte(operation+":"), payload...))
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// PurgeIdempotencyKeys periodically deletes expired idempotency keys until the context is done.
+func (s *Service) PurgeIdempotencyKeys(ctx context.Context) {
+	if s.idempotencyTTL <= 0 || s.purgeInterval <= 0 {
+		return
+	}
+
+	l := logger.GetLoggerFromCtx(ctx)
+
+	ticker := time.NewTicker(s.purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.repo.DeleteExpiredIdempotencyRecords(ctx, s.timestamp())
+			if err != nil {
+				l.Error(ctx, "failed to purge idempotency keys", zap.String("err", err.Error()))
+				continue
+			}
+
+			l.Debug(ctx, "Purged idempotency keys", zap.Int64("deleted", deleted))
+		}
+	}
+}
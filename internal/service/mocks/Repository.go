@@ -7,6 +7,12 @@ import (
 	model "ozon-tesk-task/internal/transport/graph/model"
 
 	mock "github.com/stretchr/testify/mock"
+
+	repository "ozon-tesk-task/internal/repository"
+
+	sql "database/sql"
+
+	time "time"
 )
 
 // Repository is an autogenerated mock type for the Repository type
@@ -88,6 +94,34 @@ func (_m *Repository) DeleteComment(ctx context.Context, commentId int32) error
 	return r0
 }
 
+// DeleteExpiredIdempotencyRecords provides a mock function with given fields: ctx, now
+func (_m *Repository) DeleteExpiredIdempotencyRecords(ctx context.Context, now time.Time) (int64, error) {
+	ret := _m.Called(ctx, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteExpiredIdempotencyRecords")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, now)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeletePost provides a mock function with given fields: ctx, postId
 func (_m *Repository) DeletePost(ctx context.Context, postId int32) error {
 	ret := _m.Called(ctx, postId)
@@ -136,9 +170,99 @@ func (_m *Repository) GetCommentById(ctx context.Context, commentId int32) (*mod
 	return r0, r1
 }
 
-// GetCommentsByPostId provides a mock function with given fields: ctx, postId, limit, offset
-func (_m *Repository) GetCommentsByPostId(ctx context.Context, postId int32, limit int32, offset int32) ([]*model.Comment, error) {
-	ret := _m.Called(ctx, postId, limit, offset)
+// GetCommentThread provides a mock function with given fields: ctx, commentId, maxDepth
+func (_m *Repository) GetCommentThread(ctx context.Context, commentId int32, maxDepth int32) (*model.Comment, error) {
+	ret := _m.Called(ctx, commentId, maxDepth)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCommentThread")
+	}
+
+	var r0 *model.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32, int32) (*model.Comment, error)); ok {
+		return rf(ctx, commentId, maxDepth)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int32, int32) *model.Comment); ok {
+		r0 = rf(ctx, commentId, maxDepth)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int32, int32) error); ok {
+		r1 = rf(ctx, commentId, maxDepth)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCommentsAfter provides a mock function with given fields: ctx, postId, afterId
+func (_m *Repository) GetCommentsAfter(ctx context.Context, postId int32, afterId int32) ([]*model.Comment, error) {
+	ret := _m.Called(ctx, postId, afterId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCommentsAfter")
+	}
+
+	var r0 []*model.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32, int32) ([]*model.Comment, error)); ok {
+		return rf(ctx, postId, afterId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int32, int32) []*model.Comment); ok {
+		r0 = rf(ctx, postId, afterId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int32, int32) error); ok {
+		r1 = rf(ctx, postId, afterId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCommentsByIds provides a mock function with given fields: ctx, ids
+func (_m *Repository) GetCommentsByIds(ctx context.Context, ids []int32) ([]*model.Comment, error) {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCommentsByIds")
+	}
+
+	var r0 []*model.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int32) ([]*model.Comment, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int32) []*model.Comment); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int32) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCommentsByPostId provides a mock function with given fields: ctx, postId, maxDepth, limit, offset
+func (_m *Repository) GetCommentsByPostId(ctx context.Context, postId int32, maxDepth int32, limit int32, offset int32) ([]*model.Comment, error) {
+	ret := _m.Called(ctx, postId, maxDepth, limit, offset)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetCommentsByPostId")
@@ -146,19 +270,49 @@ func (_m *Repository) GetCommentsByPostId(ctx context.Context, postId int32, lim
 
 	var r0 []*model.Comment
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int32, int32, int32) ([]*model.Comment, error)); ok {
-		return rf(ctx, postId, limit, offset)
+	if rf, ok := ret.Get(0).(func(context.Context, int32, int32, int32, int32) ([]*model.Comment, error)); ok {
+		return rf(ctx, postId, maxDepth, limit, offset)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int32, int32, int32) []*model.Comment); ok {
-		r0 = rf(ctx, postId, limit, offset)
+	if rf, ok := ret.Get(0).(func(context.Context, int32, int32, int32, int32) []*model.Comment); ok {
+		r0 = rf(ctx, postId, maxDepth, limit, offset)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*model.Comment)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int32, int32, int32) error); ok {
-		r1 = rf(ctx, postId, limit, offset)
+	if rf, ok := ret.Get(1).(func(context.Context, int32, int32, int32, int32) error); ok {
+		r1 = rf(ctx, postId, maxDepth, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetIdempotencyRecord provides a mock function with given fields: ctx, scope, key, now
+func (_m *Repository) GetIdempotencyRecord(ctx context.Context, scope string, key string, now time.Time) (*repository.IdempotencyRecord, error) {
+	ret := _m.Called(ctx, scope, key, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetIdempotencyRecord")
+	}
+
+	var r0 *repository.IdempotencyRecord
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) (*repository.IdempotencyRecord, error)); ok {
+		return rf(ctx, scope, key, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) *repository.IdempotencyRecord); ok {
+		r0 = rf(ctx, scope, key, now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.IdempotencyRecord)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(ctx, scope, key, now)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -226,6 +380,36 @@ func (_m *Repository) GetPostByIdWithComments(ctx context.Context, id int32) (*m
 	return r0, r1
 }
 
+// GetPostsByIds provides a mock function with given fields: ctx, ids
+func (_m *Repository) GetPostsByIds(ctx context.Context, ids []int32) ([]*model.Post, error) {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPostsByIds")
+	}
+
+	var r0 []*model.Post
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int32) ([]*model.Post, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int32) []*model.Post); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Post)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int32) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListPosts provides a mock function with given fields: ctx, limit, offset
 func (_m *Repository) ListPosts(ctx context.Context, limit int32, offset int32) ([]*model.Post, error) {
 	ret := _m.Called(ctx, limit, offset)
@@ -286,6 +470,78 @@ func (_m *Repository) ListPostsWithComments(ctx context.Context, limit int32, of
 	return r0, r1
 }
 
+// SaveIdempotencyRecord provides a mock function with given fields: ctx, record
+func (_m *Repository) SaveIdempotencyRecord(ctx context.Context, record *repository.IdempotencyRecord) error {
+	ret := _m.Called(ctx, record)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveIdempotencyRecord")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *repository.IdempotencyRecord) error); ok {
+		r0 = rf(ctx, record)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WithinReadOnlyTransaction provides a mock function with given fields: ctx, isolation, fn
+func (_m *Repository) WithinReadOnlyTransaction(ctx context.Context, isolation sql.IsolationLevel, fn func(context.Context) error) error {
+	ret := _m.Called(ctx, isolation, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WithinReadOnlyTransaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, sql.IsolationLevel, func(context.Context) error) error); ok {
+		r0 = rf(ctx, isolation, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WithinSavepoint provides a mock function with given fields: ctx, fn
+func (_m *Repository) WithinSavepoint(ctx context.Context, fn func(context.Context) error) error {
+	ret := _m.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WithinSavepoint")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(context.Context) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WithinTransaction provides a mock function with given fields: ctx, isolation, fn
+func (_m *Repository) WithinTransaction(ctx context.Context, isolation sql.IsolationLevel, fn func(context.Context) error) error {
+	ret := _m.Called(ctx, isolation, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WithinTransaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, sql.IsolationLevel, func(context.Context) error) error); ok {
+		r0 = rf(ctx, isolation, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewRepository creates a new instance of Repository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewRepository(t interface {
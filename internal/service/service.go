@@ -2,31 +2,73 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"ozon-tesk-task/internal/config"
 	"ozon-tesk-task/internal/repository"
 	"ozon-tesk-task/internal/transport/graph/model"
 	"ozon-tesk-task/pkg/pointer"
+	"time"
 )
 
+type UnitOfWork interface {
+	WithinTransaction(ctx context.Context, isolation sql.IsolationLevel, fn func(ctx context.Context) error) error
+	WithinReadOnlyTransaction(ctx context.Context, isolation sql.IsolationLevel, fn func(ctx context.Context) error) error
+	WithinSavepoint(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 //go:generate go run github.com/vektra/mockery/v2@latest --name Repository
 type Repository interface {
+	UnitOfWork
 	ListPosts(ctx context.Context, limit, offset int32) ([]*model.Post, error)
 	ListPostsWithComments(ctx context.Context, limit, offset int32) ([]*model.Post, error)
 	CreatePost(ctx context.Context, post *model.Post) (int32, error)
 	GetPostById(ctx context.Context, id int32) (*model.Post, error)
 	GetPostByIdWithComments(ctx context.Context, id int32) (*model.Post, error)
+	GetPostsByIds(ctx context.Context, ids []int32) ([]*model.Post, error)
 	CreateComment(ctx context.Context, comment *model.Comment) (int32, error)
 	GetCommentById(ctx context.Context, commentId int32) (*model.Comment, error)
-	GetCommentsByPostId(ctx context.Context, postId int32, limit, offset int32) ([]*model.Comment, error)
+	GetCommentsByIds(ctx context.Context, ids []int32) ([]*model.Comment, error)
+	GetCommentsAfter(ctx context.Context, postId int32, afterId int32) ([]*model.Comment, error)
+	GetCommentsByPostId(ctx context.Context, postId int32, maxDepth int32, limit, offset int32) ([]*model.Comment, error)
+	GetCommentThread(ctx context.Context, commentId int32, maxDepth int32) (*model.Comment, error)
 	DeletePost(ctx context.Context, postId int32) error
 	DeleteComment(ctx context.Context, commentId int32) error
+	GetIdempotencyRecord(ctx context.Context, scope string, key string, now time.Time) (*repository.IdempotencyRecord, error)
+	SaveIdempotencyRecord(ctx context.Context, record *repository.IdempotencyRecord) error
+	DeleteExpiredIdempotencyRecords(ctx context.Context, now time.Time) (int64, error)
 }
 
 type Service struct {
-	repo Repository
+	repo            Repository
+	maxCommentDepth int32
+	depthPolicy     string
+	idempotencyTTL  time.Duration
+	purgeInterval   time.Duration
+	maxBatchSize    int
+	now             func() time.Time
+}
+
+func New(repo Repository, cfg *config.Config) *Service {
+	return &Service{
+		repo:            repo,
+		maxCommentDepth: int32(cfg.MaxCommentDepth),
+		depthPolicy:     cfg.CommentDepthPolicy,
+		idempotencyTTL:  cfg.IdempotencyKeyTTL,
+		purgeInterval:   cfg.IdempotencyPurgeInterval,
+		maxBatchSize:    cfg.MaxBatchSize,
+		now:             time.Now,
+	}
 }
 
-func New(repo Repository) *Service {
-	return &Service{repo}
+// timestamp returns the current time in UTC, truncated to the microsecond
+// precision the databases store.
+func (s *Service) timestamp() time.Time {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+
+	return now().UTC().Truncate(time.Microsecond)
 }
 
 func (s *Service) ListPosts(ctx context.Context, limit, offset int32, withComments bool) ([]*model.Post, error) {
@@ -37,13 +79,31 @@ func (s *Service) ListPosts(ctx context.Context, limit, offset int32, withCommen
 	return s.repo.ListPosts(ctx, limit, offset)
 }
 
-func (s *Service) CreatePost(ctx context.Context, post *model.Post) (*model.Post, error) {
+// CreatePost creates the post once per client and idempotency key, an empty key disables the check.
+func (s *Service) CreatePost(ctx context.Context, post *model.Post, idempotencyKey string) (*model.Post, error) {
+	request := struct {
+		Title         string
+		Content       string
+		AllowComments bool
+	}{post.Title, post.Content, post.AllowComments}
+
+	created, _, err := runIdempotent(ctx, s, idempotencyKey, "createPost", request, func(ctx context.Context) (*model.Post, error) {
+		return s.createPost(ctx, post)
+	})
+
+	return created, err
+}
+
+func (s *Service) createPost(ctx context.Context, post *model.Post) (*model.Post, error) {
+	post.CreatedAt = s.timestamp()
+
 	id, err := s.repo.CreatePost(ctx, post)
 	if err != nil {
 		return nil, err
 	}
 
 	post.ID = id
+	post.LastActivityAt = post.CreatedAt
 
 	return post, nil
 }
@@ -63,47 +123,185 @@ func (s *Service) GetPostById(ctx context.Context, id int32, withComments bool)
 	return s.repo.GetPostById(ctx, id)
 }
 
-func (s *Service) CreateComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
-	postId := comment.PostID
-	post, err := s.repo.GetPostById(ctx, postId)
+// CreateComment creates the comment once per client and idempotency key, an empty key disables the check.
+// replayed reports that the comment was created by an earlier request with the same key.
+func (s *Service) CreateComment(ctx context.Context, comment *model.Comment, idempotencyKey string) (created *model.Comment, replayed bool, err error) {
+	request := struct {
+		PostID   int32
+		ParentID *int32
+		Content  string
+	}{comment.PostID, comment.ParentID, comment.Content}
+
+	return runIdempotent(ctx, s, idempotencyKey, "createComment", request, func(ctx context.Context) (*model.Comment, error) {
+		return s.createComment(ctx, comment)
+	})
+}
+
+func (s *Service) createComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
+	comment.CreatedAt = s.timestamp()
+
+	err := s.repo.WithinTransaction(ctx, sql.LevelSerializable, func(ctx context.Context) error {
+		postId := comment.PostID
+		post, err := s.repo.GetPostById(ctx, postId)
+		if err != nil {
+			return err
+		}
+		if !post.AllowComments {
+			return repository.ErrCommentsNotAllowed
+		}
+
+		parentId := pointer.Deref(comment.ParentID, 0)
+		if parentId != 0 {
+			comm, err := s.repo.GetCommentById(ctx, parentId)
+
+			if err != nil {
+				return err
+			}
+
+			if comm.PostID != postId {
+				return repository.ErrMatchCommentWithPost
+			}
+
+			if s.maxCommentDepth > 0 && comm.Depth >= s.maxCommentDepth {
+				if s.depthPolicy == config.DepthPolicyReject {
+					return repository.ErrCommentTooDeep
+				}
+
+				if comm, err = s.flattenReply(ctx, comment, comm); err != nil {
+					return err
+				}
+			}
+
+			comment.Depth = comm.Depth + 1
+		}
+
+		id, err := s.repo.CreateComment(ctx, comment)
+		if err != nil {
+			return err
+		}
+
+		comment.ID = id
+
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	if !post.AllowComments {
-		return nil, repository.ErrCommentsNotAllowed
-	}
 
-	parentId := pointer.Deref(comment.ParentID, 0)
-	if parentId != 0 {
-		comm, err := s.repo.GetCommentById(ctx, parentId)
+	return comment, nil
+}
 
-		if err != nil {
+// flattenReply moves a reply to the deepest ancestor of its parent that still accepts replies
+// and quotes the original parent instead. It returns the new parent.
+func (s *Service) flattenReply(ctx context.Context, comment *model.Comment, parent *model.Comment) (*model.Comment, error) {
+	quotedId := parent.ID
+	comment.QuotedID = &quotedId
+
+	for parent.Depth >= s.maxCommentDepth && parent.ParentID != nil {
+		var err error
+		if parent, err = s.repo.GetCommentById(ctx, *parent.ParentID); err != nil {
 			return nil, err
 		}
+	}
 
-		if comm.PostID != postId {
-			return nil, repository.ErrMatchCommentWithPost
+	parentId := parent.ID
+	comment.ParentID = &parentId
+
+	return parent, nil
+}
+
+func (s *Service) GetComments(ctx context.Context, postId int32, maxDepth int32, limit, offset int32) ([]*model.Comment, error) {
+	var comments []*model.Comment
+
+	err := s.repo.WithinReadOnlyTransaction(ctx, sql.LevelRepeatableRead, func(ctx context.Context) error {
+		post, err := s.repo.GetPostById(ctx, postId)
+		if err != nil {
+			return err
 		}
+		if !post.AllowComments {
+			return repository.ErrCommentsNotAllowed
+		}
+
+		comments, err = s.repo.GetCommentsByPostId(ctx, postId, maxDepth, limit, offset)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	id, err := s.repo.CreateComment(ctx, comment)
+	return comments, nil
+}
+
+// GetPostsByIds returns the posts in the order of the ids, missing posts are nil.
+func (s *Service) GetPostsByIds(ctx context.Context, ids []int32) ([]*model.Post, error) {
+	posts, err := s.repo.GetPostsByIds(ctx, ids)
 	if err != nil {
 		return nil, err
 	}
 
-	comment.ID = id
+	return inOrder(ids, posts, func(p *model.Post) int32 { return p.ID }), nil
+}
 
-	return comment, nil
+func (s *Service) GetCommentById(ctx context.Context, commentId int32) (*model.Comment, error) {
+	return s.repo.GetCommentById(ctx, commentId)
+}
+
+// GetCommentsByIds returns the comments in the order of the ids, missing comments are nil.
+func (s *Service) GetCommentsByIds(ctx context.Context, ids []int32) ([]*model.Comment, error) {
+	comments, err := s.repo.GetCommentsByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return inOrder(ids, comments, func(c *model.Comment) int32 { return c.ID }), nil
 }
 
-func (s *Service) GetComments(ctx context.Context, postId int32, limit, offset int32) ([]*model.Comment, error) {
-	post, err := s.repo.GetPostById(ctx, postId)
+// GetCommentsSince returns the comments of the post created after the comment since, e.g. to catch up
+// a subscriber with the comments it missed.
+func (s *Service) GetCommentsSince(ctx context.Context, postId int32, since int32) ([]*model.Comment, error) {
+	return s.repo.GetCommentsAfter(ctx, postId, since)
+}
+
+func (s *Service) GetCommentThread(ctx context.Context, commentId int32, maxDepth int32) (*model.Comment, error) {
+	var thread *model.Comment
+
+	err := s.repo.WithinReadOnlyTransaction(ctx, sql.LevelRepeatableRead, func(ctx context.Context) error {
+		comment, err := s.repo.GetCommentById(ctx, commentId)
+		if err != nil {
+			return err
+		}
+
+		post, err := s.repo.GetPostById(ctx, comment.PostID)
+		if err != nil {
+			return err
+		}
+		if !post.AllowComments {
+			return repository.ErrCommentsNotAllowed
+		}
+
+		thread, err = s.repo.GetCommentThread(ctx, commentId, maxDepth)
+
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	if !post.AllowComments {
-		return nil, repository.ErrCommentsNotAllowed
+
+	return thread, nil
+}
+
+// inOrder arranges the items by the ids, an item is repeated for a repeated id and missing ones are nil.
+func inOrder[T any](ids []int32, items []*T, id func(*T) int32) []*T {
+	byId := make(map[int32]*T, len(items))
+	for _, item := range items {
+		byId[id(item)] = item
+	}
+
+	result := make([]*T, len(ids))
+	for i, id := range ids {
+		result[i] = byId[id]
 	}
 
-	return s.repo.GetCommentsByPostId(ctx, postId, limit, offset)
+	return result
 }
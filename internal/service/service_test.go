@@ -2,15 +2,30 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"ozon-tesk-task/internal/auth"
+	"ozon-tesk-task/internal/config"
 	"ozon-tesk-task/internal/repository"
 	"ozon-tesk-task/internal/service/mocks"
 	"ozon-tesk-task/internal/transport/graph/model"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
 
+var testNow = time.Date(2025, 1, 2, 3, 4, 5, 0, time.FixedZone("UTC+3", 3*60*60))
+
+func fixedClock() time.Time {
+	return testNow
+}
+
+func runInTransaction(ctx context.Context, isolation sql.IsolationLevel, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
 func TestService_ListPosts(t *testing.T) {
 	type (
 		mockBehavior func(r *mocks.Repository, limit, offset int32)
@@ -249,9 +264,11 @@ func TestService_CreateComment(t *testing.T) {
 				r.On("CreateComment", mock.Anything, comment).Return(int32(8), nil)
 			},
 			want: &model.Comment{
-				ID:       8,
-				PostID:   1,
-				ParentID: func() *int32 { v := int32(1); return &v }(),
+				ID:        8,
+				PostID:    1,
+				ParentID:  func() *int32 { v := int32(1); return &v }(),
+				Depth:     1,
+				CreatedAt: testNow.UTC(),
 			},
 			wantErr: false,
 		},
@@ -261,11 +278,13 @@ func TestService_CreateComment(t *testing.T) {
 			r := mocks.NewRepository(t)
 			s := &Service{
 				repo: r,
+				now:  fixedClock,
 			}
 
+			r.On("WithinTransaction", mock.Anything, sql.LevelSerializable, mock.Anything).Return(runInTransaction)
 			tt.repoMock(r, tt.args.comment)
 
-			got, err := s.CreateComment(tt.args.ctx, tt.args.comment)
+			got, _, err := s.CreateComment(tt.args.ctx, tt.args.comment, "")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Service.CreateComment() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -276,3 +295,224 @@ func TestService_CreateComment(t *testing.T) {
 		})
 	}
 }
+
+func TestService_CreateComment_MaxDepth(t *testing.T) {
+	int32Ptr := func(v int32) *int32 { return &v }
+
+	tests := []struct {
+		name     string
+		policy   string
+		repoMock func(r *mocks.Repository)
+		want     *model.Comment
+		wantErr  error
+	}{
+		{
+			name:   "Reply is rejected",
+			policy: config.DepthPolicyReject,
+			repoMock: func(r *mocks.Repository) {
+				r.On("GetCommentById", mock.Anything, int32(3)).Return(&model.Comment{ID: 3, PostID: 1, ParentID: int32Ptr(2), Depth: 2}, nil)
+			},
+			wantErr: repository.ErrCommentTooDeep,
+		},
+		{
+			name:   "Reply is flattened",
+			policy: config.DepthPolicyFlatten,
+			repoMock: func(r *mocks.Repository) {
+				r.On("GetCommentById", mock.Anything, int32(3)).Return(&model.Comment{ID: 3, PostID: 1, ParentID: int32Ptr(2), Depth: 2}, nil)
+				r.On("GetCommentById", mock.Anything, int32(2)).Return(&model.Comment{ID: 2, PostID: 1, ParentID: int32Ptr(1), Depth: 1}, nil)
+				r.On("CreateComment", mock.Anything, mock.Anything).Return(int32(8), nil)
+			},
+			want: &model.Comment{
+				ID:        8,
+				PostID:    1,
+				ParentID:  int32Ptr(2),
+				QuotedID:  int32Ptr(3),
+				Depth:     2,
+				CreatedAt: testNow.UTC(),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mocks.NewRepository(t)
+			s := New(r, &config.Config{CommentsConfig: config.CommentsConfig{MaxCommentDepth: 2, CommentDepthPolicy: tt.policy}})
+			s.now = fixedClock
+
+			r.On("WithinTransaction", mock.Anything, sql.LevelSerializable, mock.Anything).Return(runInTransaction)
+			r.On("GetPostById", mock.Anything, int32(1)).Return(&model.Post{AllowComments: true}, nil)
+			tt.repoMock(r)
+
+			got, _, err := s.CreateComment(context.Background(), &model.Comment{PostID: 1, ParentID: int32Ptr(3)}, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Service.CreateComment() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Service.CreateComment() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_CreatePost(t *testing.T) {
+	r := mocks.NewRepository(t)
+	s := &Service{
+		repo: r,
+		now:  fixedClock,
+	}
+
+	r.On("CreatePost", mock.Anything, mock.Anything).Return(int32(5), nil)
+
+	got, err := s.CreatePost(context.Background(), &model.Post{Title: "title"}, "")
+	if err != nil {
+		t.Fatalf("Service.CreatePost() error = %v", err)
+	}
+
+	want := &model.Post{
+		ID:             5,
+		Title:          "title",
+		CreatedAt:      testNow.UTC(),
+		LastActivityAt: testNow.UTC(),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Service.CreatePost() = %+v, want %+v", got, want)
+	}
+}
+
+func TestService_GetPostsByIds(t *testing.T) {
+	r := mocks.NewRepository(t)
+	s := &Service{repo: r}
+
+	first, third := &model.Post{ID: 1}, &model.Post{ID: 3}
+
+	r.On("GetPostsByIds", mock.Anything, []int32{3, 2, 1, 3}).Return([]*model.Post{first, third}, nil)
+
+	got, err := s.GetPostsByIds(context.Background(), []int32{3, 2, 1, 3})
+	if err != nil {
+		t.Fatalf("Service.GetPostsByIds() error = %v", err)
+	}
+
+	want := []*model.Post{third, nil, first, third}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Service.GetPostsByIds() = %v, want %v", got, want)
+	}
+}
+
+func TestService_IdempotencyKey(t *testing.T) {
+	ctx := auth.WithUser(context.Background(), 1)
+	sameAuthor := auth.WithClient(context.Background(), "1.1.1.1", 1)
+
+	now := testNow
+	s := New(repository.NewMemory(), &config.Config{IdempotencyConfig: config.IdempotencyConfig{IdempotencyKeyTTL: time.Hour}})
+	s.now = func() time.Time { return now }
+
+	post, err := s.CreatePost(ctx, &model.Post{Title: "title", Content: "content", AllowComments: true, Author: 1}, "post-key")
+	if err != nil {
+		t.Fatalf("Service.CreatePost() error = %v", err)
+	}
+
+	replayedPost, err := s.CreatePost(ctx, &model.Post{Title: "title", Content: "content", AllowComments: true, Author: 1}, "post-key")
+	if err != nil || !reflect.DeepEqual(replayedPost, post) {
+		t.Errorf("Service.CreatePost() = %+v, %v, want %+v", replayedPost, err, post)
+	}
+
+	newComment := func(content string) *model.Comment {
+		return &model.Comment{PostID: post.ID, Author: 1, Content: content}
+	}
+
+	first, replayed, err := s.CreateComment(ctx, newComment("comment"), "key")
+	if err != nil || replayed {
+		t.Fatalf("Service.CreateComment() replayed = %v, error = %v", replayed, err)
+	}
+
+	second, replayed, err := s.CreateComment(ctx, newComment("comment"), "key")
+	if err != nil || !replayed || !reflect.DeepEqual(second, first) {
+		t.Errorf("Service.CreateComment() = %+v, %v, %v, want the replayed %+v", second, replayed, err, first)
+	}
+
+	if _, _, err := s.CreateComment(ctx, newComment("another comment"), "key"); !errors.Is(err, repository.ErrIdempotencyKeyReused) {
+		t.Errorf("Service.CreateComment() error = %v, want %v", err, repository.ErrIdempotencyKeyReused)
+	}
+
+	// the key is scoped by the client, not by the author the client claims to be
+	other, replayed, err := s.CreateComment(sameAuthor, newComment("comment"), "key")
+	if err != nil || replayed || other.ID == first.ID {
+		t.Errorf("Service.CreateComment() of another client = %+v, %v, %v", other, replayed, err)
+	}
+
+	// an anonymous retry comes over a new connection, but from the same client
+	anonymous, _, err := s.CreateComment(auth.WithConnection(sameAuthor, "1.1.1.1:1000"), newComment("anonymous comment"), "anonymous-key")
+	if err != nil {
+		t.Fatalf("Service.CreateComment() error = %v", err)
+	}
+	retried, replayed, err := s.CreateComment(auth.WithConnection(sameAuthor, "1.1.1.1:1001"), newComment("anonymous comment"), "anonymous-key")
+	if err != nil || !replayed || retried.ID != anonymous.ID {
+		t.Errorf("Service.CreateComment() of an anonymous retry = %+v, %v, %v, want the replayed %+v", retried, replayed, err, anonymous)
+	}
+
+	unscoped, replayed, err := s.CreateComment(context.Background(), newComment("comment"), "key")
+	if err != nil || replayed || unscoped.ID == first.ID {
+		t.Errorf("Service.CreateComment() without a client = %+v, %v, %v", unscoped, replayed, err)
+	}
+
+	now = now.Add(time.Hour)
+
+	expired, replayed, err := s.CreateComment(ctx, newComment("comment"), "key")
+	if err != nil || replayed || expired.ID == first.ID {
+		t.Errorf("Service.CreateComment() after the TTL = %+v, %v, %v", expired, replayed, err)
+	}
+}
+
+func TestService_Batch(t *testing.T) {
+	ctx := context.Background()
+
+	s := New(repository.NewMemory(), &config.Config{BatchConfig: config.BatchConfig{MaxBatchSize: 3}})
+
+	post, err := s.CreatePost(ctx, &model.Post{Title: "title", Content: "content", AllowComments: true}, "")
+	if err != nil {
+		t.Fatalf("Service.CreatePost() error = %v", err)
+	}
+
+	results, err := s.CreateComments(ctx, []*model.Comment{
+		{PostID: post.ID, Content: "root"},
+		{PostID: post.ID + 1, Content: "wrong post"},
+		{PostID: post.ID, ParentID: func() *int32 { v := int32(1); return &v }(), Content: "reply"},
+	}, []string{"", "", ""})
+	if err != nil {
+		t.Fatalf("Service.CreateComments() error = %v", err)
+	}
+
+	if results[0].Err != nil || results[0].Comment.ID != 1 {
+		t.Errorf("Service.CreateComments()[0] = %+v", results[0])
+	}
+	if !errors.Is(results[1].Err, repository.ErrWrongPostId) || results[1].Comment != nil {
+		t.Errorf("Service.CreateComments()[1] = %+v, want error %v", results[1], repository.ErrWrongPostId)
+	}
+	if results[2].Err != nil || results[2].Comment.ID != 2 || results[2].Comment.Depth != 1 {
+		t.Errorf("Service.CreateComments()[2] = %+v", results[2])
+	}
+
+	errs, err := s.DeleteComments(ctx, []int32{1, 2, 42})
+	if err != nil {
+		t.Fatalf("Service.DeleteComments() error = %v", err)
+	}
+
+	want := []error{repository.ErrCommentHasReplies, nil, repository.ErrWrongCommentId}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("Service.DeleteComments() = %v, want %v", errs, want)
+	}
+
+	if _, err := s.DeletePosts(ctx, []int32{1, 2, 3, 4}); !errors.Is(err, repository.ErrBatchTooLarge) {
+		t.Errorf("Service.DeletePosts() error = %v, want %v", err, repository.ErrBatchTooLarge)
+	}
+
+	errs, err = s.DeletePosts(ctx, []int32{post.ID, post.ID})
+	if err != nil {
+		t.Fatalf("Service.DeletePosts() error = %v", err)
+	}
+
+	want = []error{nil, repository.ErrWrongPostId}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("Service.DeletePosts() = %v, want %v", errs, want)
+	}
+}
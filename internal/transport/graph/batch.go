@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/internal/transport/graph/model"
+)
+
+// itemError presents the error of a batch item like ErrorPresenter does.
+func itemError(err error) *model.ItemError {
+	if err == nil {
+		return nil
+	}
+
+	var domainErr *repository.Error
+	if errors.As(err, &domainErr) {
+		return &model.ItemError{Code: string(domainErr.Code), Message: domainErr.Message}
+	}
+
+	return &model.ItemError{Code: codeInternal, Message: "internal server error"}
+}
+
+// deleteBatch deletes the items with valid ids and reports invalid ids as failed items.
+func deleteBatch(ctx context.Context, nodeType string, ids []string, deleteItems func(ctx context.Context, ids []int32) ([]error, error)) ([]*model.DeleteResult, error) {
+	results := make([]*model.DeleteResult, len(ids))
+
+	var (
+		valid     []int32
+		positions []int
+	)
+
+	for i, id := range ids {
+		results[i] = &model.DeleteResult{ID: id}
+
+		value, err := parseID(nodeType, id)
+		if err != nil {
+			results[i].Error = itemError(err)
+			continue
+		}
+
+		valid = append(valid, value)
+		positions = append(positions, i)
+	}
+
+	errs, err := deleteItems(ctx, valid)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, err := range errs {
+		results[positions[i]].Error = itemError(err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,119 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/internal/transport/graph/mocks"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"ozon-tesk-task/pkg/logger"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_mutationResolver_CreateComments(t *testing.T) {
+	s := mocks.NewService(t)
+	log, _ := logger.New("test")
+	p := mocks.NewPubSub(t)
+
+	r := &mutationResolver{
+		Resolver: &Resolver{s, log, p},
+	}
+
+	key := "retry-1"
+	inputs := []*model.CreateCommentInput{
+		{PostID: "1", Content: "created"},
+		{PostID: "invalid", Content: "invalid"},
+		{PostID: "1", Content: "replayed", ClientMutationID: &key},
+		{PostID: "2", Content: "failed"},
+	}
+
+	created := &model.Comment{ID: 1, PostID: 1, Content: "created"}
+	replayed := &model.Comment{ID: 2, PostID: 1, Content: "replayed"}
+
+	s.On("CreateComments", mock.Anything, []*model.Comment{
+		{PostID: 1, Content: "created"},
+		{PostID: 1, Content: "replayed"},
+		{PostID: 2, Content: "failed"},
+	}, []string{"", key, ""}).Return([]*model.CommentResult{
+		{Comment: created},
+		{Comment: replayed, Replayed: true},
+		{Err: repository.ErrWrongPostId},
+	}, nil)
+	p.On("Publish", mock.Anything, created).Once()
+
+	got, err := r.CreateComments(context.Background(), inputs)
+	if err != nil {
+		t.Fatalf("mutationResolver.CreateComments() error = %v", err)
+	}
+
+	want := []*model.CreateCommentResult{
+		{Comment: created},
+		{Error: &model.ItemError{Code: string(repository.CodeValidation), Message: repository.ErrInvalidId.Message}},
+		{Comment: replayed},
+		{Error: &model.ItemError{Code: string(repository.CodeNotFound), Message: repository.ErrWrongPostId.Message}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mutationResolver.CreateComments() = %v, want %v", got, want)
+	}
+}
+
+func Test_mutationResolver_DeleteComments(t *testing.T) {
+	s := mocks.NewService(t)
+	log, _ := logger.New("test")
+	p := mocks.NewPubSub(t)
+
+	r := &mutationResolver{
+		Resolver: &Resolver{s, log, p},
+	}
+
+	s.On("DeleteComments", mock.Anything, []int32{1, 2}).Return([]error{nil, repository.ErrCommentHasReplies}, nil)
+
+	got, err := r.DeleteComments(context.Background(), []string{"1", model.EncodeID(model.NodeTypePost, 3), model.EncodeID(model.NodeTypeComment, 2)})
+	if err != nil {
+		t.Fatalf("mutationResolver.DeleteComments() error = %v", err)
+	}
+
+	want := []*model.DeleteResult{
+		{ID: "1"},
+		{ID: model.EncodeID(model.NodeTypePost, 3), Error: &model.ItemError{Code: string(repository.CodeValidation), Message: repository.ErrInvalidId.Message}},
+		{ID: model.EncodeID(model.NodeTypeComment, 2), Error: &model.ItemError{Code: string(repository.CodeConflict), Message: repository.ErrCommentHasReplies.Message}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mutationResolver.DeleteComments() = %v, want %v", got, want)
+	}
+
+	s.On("DeleteComments", mock.Anything, []int32{5}).Return(nil, errors.New("internal error"))
+
+	if _, err := r.DeleteComments(context.Background(), []string{"5"}); err == nil {
+		t.Error("mutationResolver.DeleteComments() error = nil")
+	}
+}
+
+func Test_mutationResolver_DeletePosts(t *testing.T) {
+	s := mocks.NewService(t)
+	log, _ := logger.New("test")
+	p := mocks.NewPubSub(t)
+
+	r := &mutationResolver{
+		Resolver: &Resolver{s, log, p},
+	}
+
+	s.On("DeletePosts", mock.Anything, []int32{1, 2}).Return([]error{nil, repository.ErrWrongPostId}, nil)
+	p.On("Evict", mock.Anything, int32(1)).Once()
+
+	got, err := r.DeletePosts(context.Background(), []string{"1", "2"})
+	if err != nil {
+		t.Fatalf("mutationResolver.DeletePosts() error = %v", err)
+	}
+
+	want := []*model.DeleteResult{
+		{ID: "1"},
+		{ID: "2", Error: &model.ItemError{Code: string(repository.CodeNotFound), Message: repository.ErrWrongPostId.Message}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mutationResolver.DeletePosts() = %v, want %v", got, want)
+	}
+}
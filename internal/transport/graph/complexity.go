@@ -0,0 +1,59 @@
+package graph
+
+import (
+	"math"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"ozon-tesk-task/pkg/pointer"
+)
+
+const defaultPageLimit = 10
+
+// NewComplexity weighs list fields by the number of items they can return. Paginated lists use their
+// limit argument, comments and replies nested into posts and comments are not paginated and use listWeight.
+func NewComplexity(listWeight int) ComplexityRoot {
+	var c ComplexityRoot
+
+	c.Query.Posts = func(childComplexity int, page *int32, limit *int32) int {
+		return listComplexity(pageLimit(limit), childComplexity)
+	}
+	c.Query.Comments = func(childComplexity int, postID string, page *int32, limit *int32, maxDepth *int32) int {
+		return listComplexity(pageLimit(limit), childComplexity)
+	}
+	c.Query.Nodes = func(childComplexity int, ids []string) int {
+		return listComplexity(len(ids), childComplexity)
+	}
+	c.Mutation.DeletePosts = func(childComplexity int, ids []string) int {
+		return listComplexity(len(ids), childComplexity)
+	}
+	c.Mutation.DeleteComments = func(childComplexity int, ids []string) int {
+		return listComplexity(len(ids), childComplexity)
+	}
+	c.Mutation.CreateComments = func(childComplexity int, inputs []*model.CreateCommentInput) int {
+		return listComplexity(len(inputs), childComplexity)
+	}
+	c.Post.Comments = func(childComplexity int) int {
+		return listComplexity(listWeight, childComplexity)
+	}
+	c.Comment.Replies = func(childComplexity int) int {
+		return listComplexity(listWeight, childComplexity)
+	}
+
+	return c
+}
+
+// pageLimit returns the limit the resolvers actually use for the limit argument.
+func pageLimit(limit *int32) int {
+	if lim := pointer.Deref(limit, defaultPageLimit); lim > 0 {
+		return int(lim)
+	}
+
+	return defaultPageLimit
+}
+
+func listComplexity(items, childComplexity int) int {
+	if childComplexity > 0 && items > (math.MaxInt-1)/childComplexity {
+		return math.MaxInt
+	}
+
+	return 1 + items*childComplexity
+}
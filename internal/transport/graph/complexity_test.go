@@ -0,0 +1,62 @@
+package graph
+
+import (
+	"math"
+	"testing"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/vektah/gqlparser/v2"
+)
+
+func TestComplexity(t *testing.T) {
+	es := NewExecutableSchema(Config{Resolvers: &Resolver{}, Complexity: NewComplexity(5)})
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{
+			name:  "Single post",
+			query: `{ post(id: 1) { id title } }`,
+			want:  3,
+		},
+		{
+			name:  "Default limit",
+			query: `{ posts { id } }`,
+			want:  11,
+		},
+		{
+			name:  "Limit argument",
+			query: `{ posts(limit: 100) { id } }`,
+			want:  101,
+		},
+		{
+			name:  "Invalid limit falls back to default",
+			query: `{ comments(postId: 1, limit: -5) { id } }`,
+			want:  11,
+		},
+		{
+			name:  "Nested replies",
+			query: `{ posts(limit: 2) { comments { replies { replies { id } } } } }`,
+			want:  1 + 2*(1+5*(1+5*(1+5*1))),
+		},
+		{
+			name:  "Deep nesting saturates",
+			query: `{ posts(limit: 1000000) { comments { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { replies { id } } } } } } } } } } } } } } } } } } } } } } } } } } } } } }`,
+			want:  math.MaxInt,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := gqlparser.LoadQuery(es.Schema(), tt.query)
+			if err != nil {
+				t.Fatalf("LoadQuery() error = %v", err)
+			}
+
+			if got := complexity.Calculate(es, doc.Operations[0], nil); got != tt.want {
+				t.Errorf("Calculate() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
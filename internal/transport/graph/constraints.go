@@ -0,0 +1,203 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"ozon-tesk-task/internal/config"
+	"ozon-tesk-task/internal/repository"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+type constraint struct {
+	minLength int
+	maxLength int
+	pattern   *regexp.Regexp
+}
+
+// ValidateConstraints checks field arguments against the @constraint directives of the input fields
+// before the resolver runs. All violations are reported at once and the resolver is skipped.
+func ValidateConstraints(schema *ast.Schema, cfg *config.Config) (graphql.FieldMiddleware, error) {
+	constraints, err := parseConstraints(schema, maxLengthLimits(cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+		fc := graphql.GetFieldContext(ctx)
+		if fc == nil || len(fc.Field.Arguments) == 0 {
+			return next(ctx)
+		}
+
+		args := fc.Field.ArgumentMap(graphql.GetOperationContext(ctx).Variables)
+
+		var violations gqlerror.List
+		for _, arg := range fc.Field.Definition.Arguments {
+			violations = append(violations, validateValue(schema, constraints, arg.Name, arg.Type, args[arg.Name])...)
+		}
+
+		if len(violations) == 0 {
+			return next(ctx)
+		}
+
+		for _, violation := range violations {
+			graphql.AddError(ctx, violation)
+		}
+
+		return nil, nil
+	}, nil
+}
+
+// InputValidator checks single values against the @constraint directives of the schema, for
+// transports that do not go through the GraphQL executor.
+type InputValidator struct {
+	constraints map[string]constraint
+}
+
+func NewInputValidator(schema *ast.Schema, cfg *config.Config) (*InputValidator, error) {
+	constraints, err := parseConstraints(schema, maxLengthLimits(cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	return &InputValidator{constraints: constraints}, nil
+}
+
+// Check returns why the value of the input field, e.g. "CreatePostInput.title", is invalid, or an empty string.
+func (v *InputValidator) Check(coordinate string, value string) string {
+	c, ok := v.constraints[coordinate]
+	if !ok {
+		return ""
+	}
+
+	return c.check(value)
+}
+
+func maxLengthLimits(cfg *config.Config) map[string]int {
+	return map[string]int{
+		"CreatePostInput.title":      cfg.PostTitleMaxLength,
+		"CreatePostInput.content":    cfg.PostContentMaxLength,
+		"CreateCommentInput.content": cfg.CommentMaxLength,
+	}
+}
+
+func parseConstraints(schema *ast.Schema, limits map[string]int) (map[string]constraint, error) {
+	constraints := make(map[string]constraint)
+
+	for _, def := range schema.Types {
+		if def.Kind != ast.InputObject {
+			continue
+		}
+
+		for _, field := range def.Fields {
+			directive := field.Directives.ForName("constraint")
+			if directive == nil {
+				continue
+			}
+
+			coordinate := def.Name + "." + field.Name
+
+			var (
+				c   constraint
+				err error
+			)
+
+			if c.minLength, err = intArgument(directive, "minLength"); err != nil {
+				return nil, fmt.Errorf("invalid minLength of %s: %w", coordinate, err)
+			}
+			if c.maxLength, err = intArgument(directive, "maxLength"); err != nil {
+				return nil, fmt.Errorf("invalid maxLength of %s: %w", coordinate, err)
+			}
+			if limit := limits[coordinate]; limit > 0 && (c.maxLength == 0 || limit < c.maxLength) {
+				c.maxLength = limit
+			}
+			if arg := directive.Arguments.ForName("pattern"); arg != nil {
+				pattern, err := regexp.Compile(arg.Value.Raw)
+				if err != nil {
+					return nil, fmt.Errorf("invalid pattern of %s: %w", coordinate, err)
+				}
+				c.pattern = pattern
+			}
+
+			constraints[coordinate] = c
+		}
+	}
+
+	return constraints, nil
+}
+
+func intArgument(directive *ast.Directive, name string) (int, error) {
+	arg := directive.Arguments.ForName(name)
+	if arg == nil {
+		return 0, nil
+	}
+
+	return strconv.Atoi(arg.Value.Raw)
+}
+
+func validateValue(schema *ast.Schema, constraints map[string]constraint, path string, typ *ast.Type, value interface{}) gqlerror.List {
+	if value == nil {
+		return nil
+	}
+
+	var violations gqlerror.List
+
+	if typ.Elem != nil {
+		items, _ := value.([]interface{})
+		for i, item := range items {
+			violations = append(violations, validateValue(schema, constraints, fmt.Sprintf("%s[%d]", path, i), typ.Elem, item)...)
+		}
+
+		return violations
+	}
+
+	def := schema.Types[typ.NamedType]
+	fields, ok := value.(map[string]interface{})
+	if def == nil || def.Kind != ast.InputObject || !ok {
+		return nil
+	}
+
+	for _, field := range def.Fields {
+		fieldPath := path + "." + field.Name
+
+		if c, ok := constraints[def.Name+"."+field.Name]; ok {
+			if s, ok := fields[field.Name].(string); ok {
+				if message := c.check(s); message != "" {
+					violations = append(violations, &gqlerror.Error{
+						Message: fmt.Sprintf("%s %s", field.Name, message),
+						Extensions: map[string]interface{}{
+							"code":  repository.CodeValidation,
+							"field": fieldPath,
+						},
+					})
+				}
+			}
+		}
+
+		violations = append(violations, validateValue(schema, constraints, fieldPath, field.Type, fields[field.Name])...)
+	}
+
+	return violations
+}
+
+func (c constraint) check(value string) string {
+	length := utf8.RuneCountInString(value)
+
+	switch {
+	case length == 0 && c.minLength > 0:
+		return "must not be empty"
+	case length < c.minLength:
+		return fmt.Sprintf("must be at least %d characters long", c.minLength)
+	case c.maxLength > 0 && length > c.maxLength:
+		return fmt.Sprintf("must be at most %d characters long", c.maxLength)
+	case c.pattern != nil && !c.pattern.MatchString(value):
+		return fmt.Sprintf("must match the pattern %s", c.pattern)
+	}
+
+	return ""
+}
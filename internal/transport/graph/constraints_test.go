@@ -0,0 +1,183 @@
+package graph
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"ozon-tesk-task/internal/config"
+	"ozon-tesk-task/internal/transport/graph/mocks"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"ozon-tesk-task/pkg/logger"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/stretchr/testify/mock"
+)
+
+type constraintResponse struct {
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func newConstraintServer(t *testing.T, s *mocks.Service, cfg *config.Config) *handler.Server {
+	t.Helper()
+
+	log, _ := logger.New("test")
+	es := NewExecutableSchema(Config{Resolvers: NewResolver(s, log, mocks.NewPubSub(t))})
+
+	constraints, err := ValidateConstraints(es.Schema(), cfg)
+	if err != nil {
+		t.Fatalf("ValidateConstraints() error = %v", err)
+	}
+
+	srv := handler.New(es)
+	srv.AddTransport(transport.POST{})
+	srv.SetErrorPresenter(ErrorPresenter)
+	srv.AroundFields(constraints)
+
+	return srv
+}
+
+func postQuery(t *testing.T, srv http.Handler, query string, variables map[string]interface{}) constraintResponse {
+	t.Helper()
+
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	var resp constraintResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+	}
+
+	return resp
+}
+
+func TestValidateConstraints(t *testing.T) {
+	const createPost = `mutation($input: CreatePostInput!) { createPost(input: $input) { id } }`
+
+	defaults := &config.Config{ValidationConfig: config.ValidationConfig{PostTitleMaxLength: 100, PostContentMaxLength: 2000, CommentMaxLength: 2000}}
+
+	tests := []struct {
+		name       string
+		cfg        *config.Config
+		input      map[string]interface{}
+		wantFields []string
+	}{
+		{
+			name:       "Empty title and content",
+			cfg:        defaults,
+			input:      map[string]interface{}{"title": "", "content": "", "allowComments": true},
+			wantFields: []string{"input.title", "input.content"},
+		},
+		{
+			name:       "Blank title",
+			cfg:        defaults,
+			input:      map[string]interface{}{"title": "   ", "content": "content", "allowComments": true},
+			wantFields: []string{"input.title"},
+		},
+		{
+			name:       "Title longer than the column",
+			cfg:        defaults,
+			input:      map[string]interface{}{"title": strings.Repeat("a", 101), "content": "content", "allowComments": true},
+			wantFields: []string{"input.title"},
+		},
+		{
+			name:       "Title limit lowered by config",
+			cfg:        &config.Config{ValidationConfig: config.ValidationConfig{PostTitleMaxLength: 10}},
+			input:      map[string]interface{}{"title": strings.Repeat("a", 11), "content": "content", "allowComments": true},
+			wantFields: []string{"input.title"},
+		},
+		{
+			name:       "Config can not raise the schema limit",
+			cfg:        &config.Config{ValidationConfig: config.ValidationConfig{PostTitleMaxLength: 1000}},
+			input:      map[string]interface{}{"title": strings.Repeat("a", 101), "content": "content", "allowComments": true},
+			wantFields: []string{"input.title"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newConstraintServer(t, mocks.NewService(t), tt.cfg)
+
+			resp := postQuery(t, srv, createPost, map[string]interface{}{"input": tt.input})
+
+			var fields []string
+			for _, err := range resp.Errors {
+				if err.Extensions["code"] != "VALIDATION" {
+					t.Errorf("error %q has code %v, want VALIDATION", err.Message, err.Extensions["code"])
+				}
+				field, _ := err.Extensions["field"].(string)
+				fields = append(fields, field)
+			}
+
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("invalid fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestValidateConstraints_Valid(t *testing.T) {
+	s := mocks.NewService(t)
+	s.On("CreatePost", mock.Anything, mock.Anything, mock.Anything).Return(&model.Post{ID: 1}, nil)
+
+	srv := newConstraintServer(t, s, &config.Config{})
+
+	title := strings.Repeat("ы", 100)
+	resp := postQuery(t, srv, `mutation { createPost(input: {title: "`+title+`", content: "content", allowComments: true}) { id } }`, nil)
+
+	if len(resp.Errors) != 0 {
+		t.Errorf("unexpected errors %+v", resp.Errors)
+	}
+}
+
+// TestConstraintsMatchMigrations makes sure the schema accepts only values that fit into the storage columns.
+func TestConstraintsMatchMigrations(t *testing.T) {
+	columns := map[string]string{
+		"CreatePostInput.title":      "posts.title",
+		"CreateCommentInput.content": "comments.content",
+	}
+
+	es := NewExecutableSchema(Config{Resolvers: &Resolver{}})
+
+	constraints, err := parseConstraints(es.Schema(), nil)
+	if err != nil {
+		t.Fatalf("parseConstraints() error = %v", err)
+	}
+
+	for _, dialect := range []string{"postgres", "sqlite"} {
+		migration, err := os.ReadFile("../../database/migrations/" + dialect + "/000001_posts.up.sql")
+		if err != nil {
+			t.Fatalf("failed to read migration: %v", err)
+		}
+
+		for coordinate, column := range columns {
+			table, name, _ := strings.Cut(column, ".")
+
+			tableDef := regexp.MustCompile(`(?s)CREATE TABLE IF NOT EXISTS ` + table + ` \((.*?)\n\);`).FindSubmatch(migration)
+			if tableDef == nil {
+				t.Fatalf("%s: table %s not found", dialect, table)
+			}
+
+			size := regexp.MustCompile(`\n\s*` + name + ` VARCHAR\((\d+)\)`).FindSubmatch(tableDef[1])
+			if size == nil {
+				t.Fatalf("%s: column %s not found", dialect, column)
+			}
+
+			if want, _ := strconv.Atoi(string(size[1])); constraints[coordinate].maxLength != want {
+				t.Errorf("%s: %s maxLength = %d, column %s is VARCHAR(%d)", dialect, coordinate, constraints[coordinate].maxLength, column, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package graph
+
+import (
+	"context"
+)
+
+// findEntities loads the entities of a gateway request with one lookup. The result is in the order of the ids,
+// an id that is invalid or does not exist resolves to null.
+func findEntities[T any](ctx context.Context, nodeType string, ids []string, load func(ctx context.Context, ids []int32) ([]*T, error)) ([]*T, error) {
+	entities := make([]*T, len(ids))
+
+	var (
+		valid     []int32
+		positions []int
+	)
+
+	for i, id := range ids {
+		value, err := parseID(nodeType, id)
+		if err != nil {
+			continue
+		}
+
+		valid = append(valid, value)
+		positions = append(positions, i)
+	}
+
+	if len(valid) == 0 {
+		return entities, nil
+	}
+
+	loaded, err := load(ctx, valid)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, entity := range loaded {
+		entities[positions[i]] = entity
+	}
+
+	return entities, nil
+}
@@ -0,0 +1,53 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.64
+
+import (
+	"context"
+	"ozon-tesk-task/internal/transport/graph/model"
+
+	"go.uber.org/zap"
+)
+
+// FindManyCommentByIDs is the resolver for the findManyCommentByIDs field.
+func (r *entityResolver) FindManyCommentByIDs(ctx context.Context, reps []*model.CommentByIDsInput) ([]*model.Comment, error) {
+	r.logs.Debug(ctx, "Resolving comment entities", zap.Int("count", len(reps)))
+
+	ids := make([]string, len(reps))
+	for i, rep := range reps {
+		ids[i] = rep.ID
+	}
+
+	comments, err := findEntities(ctx, model.NodeTypeComment, ids, r.service.GetCommentsByIds)
+	if err != nil {
+		r.logs.Error(ctx, "failed to resolve comment entities", zap.String("err", err.Error()))
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// FindManyPostByIDs is the resolver for the findManyPostByIDs field.
+func (r *entityResolver) FindManyPostByIDs(ctx context.Context, reps []*model.PostByIDsInput) ([]*model.Post, error) {
+	r.logs.Debug(ctx, "Resolving post entities", zap.Int("count", len(reps)))
+
+	ids := make([]string, len(reps))
+	for i, rep := range reps {
+		ids[i] = rep.ID
+	}
+
+	posts, err := findEntities(ctx, model.NodeTypePost, ids, r.service.GetPostsByIds)
+	if err != nil {
+		r.logs.Error(ctx, "failed to resolve post entities", zap.String("err", err.Error()))
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// Entity returns EntityResolver implementation.
+func (r *Resolver) Entity() EntityResolver { return &entityResolver{r} }
+
+type entityResolver struct{ *Resolver }
@@ -0,0 +1,75 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"ozon-tesk-task/internal/transport/graph/mocks"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"ozon-tesk-task/pkg/logger"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_entityResolver_FindManyPostByIDs(t *testing.T) {
+	post := &model.Post{ID: 1, Title: "post"}
+
+	tests := []struct {
+		name        string
+		ids         []string
+		serviceMock func(s *mocks.Service)
+		want        []*model.Post
+		wantErr     bool
+	}{
+		{
+			name: "Posts are loaded at once",
+			ids:  []string{post.GetID(), "garbage", model.EncodeID(model.NodeTypePost, 2), "1"},
+			serviceMock: func(s *mocks.Service) {
+				s.On("GetPostsByIds", mock.Anything, []int32{1, 2, 1}).Return([]*model.Post{post, nil, post}, nil)
+			},
+			want: []*model.Post{post, nil, nil, post},
+		},
+		{
+			name:        "Only invalid ids",
+			ids:         []string{model.EncodeID(model.NodeTypeComment, 1)},
+			serviceMock: func(s *mocks.Service) {},
+			want:        []*model.Post{nil},
+		},
+		{
+			name: "Internal error",
+			ids:  []string{post.GetID()},
+			serviceMock: func(s *mocks.Service) {
+				s.On("GetPostsByIds", mock.Anything, []int32{1}).Return(nil, errors.New("internal error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mocks.NewService(t)
+			log, _ := logger.New("test")
+			p := mocks.NewPubSub(t)
+
+			r := &entityResolver{
+				Resolver: &Resolver{s, log, p},
+			}
+
+			tt.serviceMock(s)
+
+			reps := make([]*model.PostByIDsInput, len(tt.ids))
+			for i, id := range tt.ids {
+				reps[i] = &model.PostByIDsInput{ID: id}
+			}
+
+			got, err := r.FindManyPostByIDs(context.Background(), reps)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("entityResolver.FindManyPostByIDs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entityResolver.FindManyPostByIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"ozon-tesk-task/internal/repository"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const codeInternal = "INTERNAL"
+
+// ErrorPresenter reports domain errors with their code and message. Errors raised by gqlgen itself, e.g. for
+// invalid arguments or too complex queries, are kept as they are and any other error is hidden behind a generic message.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	presented := graphql.DefaultErrorPresenter(ctx, err)
+
+	var domainErr *repository.Error
+	if errors.As(err, &domainErr) {
+		presented.Message = domainErr.Message
+		presented.Extensions = map[string]interface{}{
+			"code": domainErr.Code,
+		}
+
+		return presented
+	}
+
+	// gqlgen wraps resolver errors in a gqlerror with their path, only errors raised by gqlgen itself,
+	// e.g. parsing or validation errors, have no cause and are shown as they are.
+	var gqlErr *gqlerror.Error
+	if errors.As(err, &gqlErr) && gqlErr.Unwrap() == nil {
+		return presented
+	}
+
+	presented.Message = "internal server error"
+	presented.Extensions = map[string]interface{}{
+		"code": codeInternal,
+	}
+
+	return presented
+}
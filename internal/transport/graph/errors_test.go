@@ -0,0 +1,89 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ozon-tesk-task/internal/repository"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+func TestErrorPresenter(t *testing.T) {
+	internalErr := errors.New(`pq: relation "posts" does not exist`)
+
+	// resolver errors reach the presenter wrapped with the path of their field
+	ctx := graphql.WithFieldContext(context.Background(), &graphql.FieldContext{Field: graphql.CollectedField{Field: &ast.Field{Alias: "post"}}})
+	path := ast.Path{ast.PathName("post")}
+
+	tests := []struct {
+		name        string
+		err         error
+		wantMessage string
+		wantCode    interface{}
+		wantPath    ast.Path
+	}{
+		{
+			name:        "Domain error",
+			err:         repository.ErrWrongPostId,
+			wantMessage: repository.ErrWrongPostId.Message,
+			wantCode:    repository.CodeNotFound,
+		},
+		{
+			name:        "Wrapped domain error",
+			err:         fmt.Errorf("loading post: %w", repository.ErrCommentsNotAllowed),
+			wantMessage: repository.ErrCommentsNotAllowed.Message,
+			wantCode:    repository.CodeCommentsLocked,
+		},
+		{
+			name:        "Validation error",
+			err:         repository.NewError(repository.CodeValidation, "title is too long"),
+			wantMessage: "title is too long",
+			wantCode:    repository.CodeValidation,
+		},
+		{
+			name:        "Graphql error",
+			err:         &gqlerror.Error{Message: "operation is too complex", Extensions: map[string]interface{}{"code": "COMPLEXITY_LIMIT_EXCEEDED"}},
+			wantMessage: "operation is too complex",
+			wantCode:    "COMPLEXITY_LIMIT_EXCEEDED",
+		},
+		{
+			name:        "Internal error",
+			err:         internalErr,
+			wantMessage: "internal server error",
+			wantCode:    codeInternal,
+		},
+		{
+			name:        "Internal error of a resolver",
+			err:         graphql.ErrorOnPath(ctx, internalErr),
+			wantMessage: "internal server error",
+			wantCode:    codeInternal,
+			wantPath:    path,
+		},
+		{
+			name:        "Domain error of a resolver",
+			err:         graphql.ErrorOnPath(ctx, repository.ErrWrongPostId),
+			wantMessage: repository.ErrWrongPostId.Message,
+			wantCode:    repository.CodeNotFound,
+			wantPath:    path,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ErrorPresenter(ctx, tt.err)
+
+			if got.Message != tt.wantMessage {
+				t.Errorf("ErrorPresenter() message = %q, want %q", got.Message, tt.wantMessage)
+			}
+			if got.Extensions["code"] != tt.wantCode {
+				t.Errorf("ErrorPresenter() code = %v, want %v", got.Extensions["code"], tt.wantCode)
+			}
+			if tt.wantPath != nil && got.Path.String() != tt.wantPath.String() {
+				t.Errorf("ErrorPresenter() path = %v, want %v", got.Path, tt.wantPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyKey returns the clientMutationId of the input or else the Idempotency-Key header of the request.
+func idempotencyKey(ctx context.Context, clientMutationId *string) string {
+	if clientMutationId != nil {
+		return *clientMutationId
+	}
+
+	if !graphql.HasOperationContext(ctx) {
+		return ""
+	}
+
+	return graphql.GetOperationContext(ctx).Headers.Get(idempotencyKeyHeader)
+}
@@ -33,33 +33,48 @@ func (_m *PubSub) Check(postId int32) bool {
 	return r0
 }
 
+// Evict provides a mock function with given fields: ctx, postId
+func (_m *PubSub) Evict(ctx context.Context, postId int32) {
+	_m.Called(ctx, postId)
+}
+
 // Publish provides a mock function with given fields: ctx, comment
 func (_m *PubSub) Publish(ctx context.Context, comment *model.Comment) {
 	_m.Called(ctx, comment)
 }
 
-// Subscribe provides a mock function with given fields: ctx, postId
-func (_m *PubSub) Subscribe(ctx context.Context, postId int32) <-chan *model.Comment {
-	ret := _m.Called(ctx, postId)
+// Subscribe provides a mock function with given fields: ctx, postId, since
+func (_m *PubSub) Subscribe(ctx context.Context, postId int32, since int32) (<-chan *model.Comment, error) {
+	ret := _m.Called(ctx, postId, since)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Subscribe")
 	}
 
 	var r0 <-chan *model.Comment
-	if rf, ok := ret.Get(0).(func(context.Context, int32) <-chan *model.Comment); ok {
-		r0 = rf(ctx, postId)
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32, int32) (<-chan *model.Comment, error)); ok {
+		return rf(ctx, postId, since)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int32, int32) <-chan *model.Comment); ok {
+		r0 = rf(ctx, postId, since)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(<-chan *model.Comment)
 		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, int32, int32) error); ok {
+		r1 = rf(ctx, postId, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // Unsubscribe provides a mock function with given fields: ctx, postId, ch
-func (_m *PubSub) Unsubscribe(ctx context.Context, postId int32, ch chan *model.Comment) {
+func (_m *PubSub) Unsubscribe(ctx context.Context, postId int32, ch <-chan *model.Comment) {
 	_m.Called(ctx, postId, ch)
 }
 
@@ -15,29 +15,66 @@ type Service struct {
 	mock.Mock
 }
 
-// CreateComment provides a mock function with given fields: ctx, comment
-func (_m *Service) CreateComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
-	ret := _m.Called(ctx, comment)
+// CreateComment provides a mock function with given fields: ctx, comment, idempotencyKey
+func (_m *Service) CreateComment(ctx context.Context, comment *model.Comment, idempotencyKey string) (*model.Comment, bool, error) {
+	ret := _m.Called(ctx, comment, idempotencyKey)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CreateComment")
 	}
 
 	var r0 *model.Comment
-	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, *model.Comment) (*model.Comment, error)); ok {
-		return rf(ctx, comment)
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Comment, string) (*model.Comment, bool, error)); ok {
+		return rf(ctx, comment, idempotencyKey)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, *model.Comment) *model.Comment); ok {
-		r0 = rf(ctx, comment)
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Comment, string) *model.Comment); ok {
+		r0 = rf(ctx, comment, idempotencyKey)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*model.Comment)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, *model.Comment) error); ok {
-		r1 = rf(ctx, comment)
+	if rf, ok := ret.Get(1).(func(context.Context, *model.Comment, string) bool); ok {
+		r1 = rf(ctx, comment, idempotencyKey)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, *model.Comment, string) error); ok {
+		r2 = rf(ctx, comment, idempotencyKey)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// CreateComments provides a mock function with given fields: ctx, comments, idempotencyKeys
+func (_m *Service) CreateComments(ctx context.Context, comments []*model.Comment, idempotencyKeys []string) ([]*model.CommentResult, error) {
+	ret := _m.Called(ctx, comments, idempotencyKeys)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateComments")
+	}
+
+	var r0 []*model.CommentResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*model.Comment, []string) ([]*model.CommentResult, error)); ok {
+		return rf(ctx, comments, idempotencyKeys)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []*model.Comment, []string) []*model.CommentResult); ok {
+		r0 = rf(ctx, comments, idempotencyKeys)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.CommentResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []*model.Comment, []string) error); ok {
+		r1 = rf(ctx, comments, idempotencyKeys)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -45,9 +82,9 @@ func (_m *Service) CreateComment(ctx context.Context, comment *model.Comment) (*
 	return r0, r1
 }
 
-// CreatePost provides a mock function with given fields: ctx, post
-func (_m *Service) CreatePost(ctx context.Context, post *model.Post) (*model.Post, error) {
-	ret := _m.Called(ctx, post)
+// CreatePost provides a mock function with given fields: ctx, post, idempotencyKey
+func (_m *Service) CreatePost(ctx context.Context, post *model.Post, idempotencyKey string) (*model.Post, error) {
+	ret := _m.Called(ctx, post, idempotencyKey)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CreatePost")
@@ -55,19 +92,19 @@ func (_m *Service) CreatePost(ctx context.Context, post *model.Post) (*model.Pos
 
 	var r0 *model.Post
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, *model.Post) (*model.Post, error)); ok {
-		return rf(ctx, post)
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Post, string) (*model.Post, error)); ok {
+		return rf(ctx, post, idempotencyKey)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, *model.Post) *model.Post); ok {
-		r0 = rf(ctx, post)
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Post, string) *model.Post); ok {
+		r0 = rf(ctx, post, idempotencyKey)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*model.Post)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, *model.Post) error); ok {
-		r1 = rf(ctx, post)
+	if rf, ok := ret.Get(1).(func(context.Context, *model.Post, string) error); ok {
+		r1 = rf(ctx, post, idempotencyKey)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -93,6 +130,36 @@ func (_m *Service) DeleteComment(ctx context.Context, commentId int32) error {
 	return r0
 }
 
+// DeleteComments provides a mock function with given fields: ctx, ids
+func (_m *Service) DeleteComments(ctx context.Context, ids []int32) ([]error, error) {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteComments")
+	}
+
+	var r0 []error
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int32) ([]error, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int32) []error); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]error)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int32) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeletePost provides a mock function with given fields: ctx, postId
 func (_m *Service) DeletePost(ctx context.Context, postId int32) error {
 	ret := _m.Called(ctx, postId)
@@ -111,9 +178,99 @@ func (_m *Service) DeletePost(ctx context.Context, postId int32) error {
 	return r0
 }
 
-// GetComments provides a mock function with given fields: ctx, postId, limit, offset
-func (_m *Service) GetComments(ctx context.Context, postId int32, limit int32, offset int32) ([]*model.Comment, error) {
-	ret := _m.Called(ctx, postId, limit, offset)
+// DeletePosts provides a mock function with given fields: ctx, ids
+func (_m *Service) DeletePosts(ctx context.Context, ids []int32) ([]error, error) {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeletePosts")
+	}
+
+	var r0 []error
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int32) ([]error, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int32) []error); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]error)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int32) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCommentById provides a mock function with given fields: ctx, commentId
+func (_m *Service) GetCommentById(ctx context.Context, commentId int32) (*model.Comment, error) {
+	ret := _m.Called(ctx, commentId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCommentById")
+	}
+
+	var r0 *model.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32) (*model.Comment, error)); ok {
+		return rf(ctx, commentId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int32) *model.Comment); ok {
+		r0 = rf(ctx, commentId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int32) error); ok {
+		r1 = rf(ctx, commentId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCommentThread provides a mock function with given fields: ctx, commentId, maxDepth
+func (_m *Service) GetCommentThread(ctx context.Context, commentId int32, maxDepth int32) (*model.Comment, error) {
+	ret := _m.Called(ctx, commentId, maxDepth)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCommentThread")
+	}
+
+	var r0 *model.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32, int32) (*model.Comment, error)); ok {
+		return rf(ctx, commentId, maxDepth)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int32, int32) *model.Comment); ok {
+		r0 = rf(ctx, commentId, maxDepth)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int32, int32) error); ok {
+		r1 = rf(ctx, commentId, maxDepth)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetComments provides a mock function with given fields: ctx, postId, maxDepth, limit, offset
+func (_m *Service) GetComments(ctx context.Context, postId int32, maxDepth int32, limit int32, offset int32) ([]*model.Comment, error) {
+	ret := _m.Called(ctx, postId, maxDepth, limit, offset)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetComments")
@@ -121,19 +278,49 @@ func (_m *Service) GetComments(ctx context.Context, postId int32, limit int32, o
 
 	var r0 []*model.Comment
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int32, int32, int32) ([]*model.Comment, error)); ok {
-		return rf(ctx, postId, limit, offset)
+	if rf, ok := ret.Get(0).(func(context.Context, int32, int32, int32, int32) ([]*model.Comment, error)); ok {
+		return rf(ctx, postId, maxDepth, limit, offset)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int32, int32, int32) []*model.Comment); ok {
-		r0 = rf(ctx, postId, limit, offset)
+	if rf, ok := ret.Get(0).(func(context.Context, int32, int32, int32, int32) []*model.Comment); ok {
+		r0 = rf(ctx, postId, maxDepth, limit, offset)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*model.Comment)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int32, int32, int32) error); ok {
-		r1 = rf(ctx, postId, limit, offset)
+	if rf, ok := ret.Get(1).(func(context.Context, int32, int32, int32, int32) error); ok {
+		r1 = rf(ctx, postId, maxDepth, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCommentsByIds provides a mock function with given fields: ctx, ids
+func (_m *Service) GetCommentsByIds(ctx context.Context, ids []int32) ([]*model.Comment, error) {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCommentsByIds")
+	}
+
+	var r0 []*model.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int32) ([]*model.Comment, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int32) []*model.Comment); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int32) error); ok {
+		r1 = rf(ctx, ids)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -171,6 +358,36 @@ func (_m *Service) GetPostById(ctx context.Context, id int32, withComments bool)
 	return r0, r1
 }
 
+// GetPostsByIds provides a mock function with given fields: ctx, ids
+func (_m *Service) GetPostsByIds(ctx context.Context, ids []int32) ([]*model.Post, error) {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPostsByIds")
+	}
+
+	var r0 []*model.Post
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int32) ([]*model.Post, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int32) []*model.Post); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Post)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int32) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListPosts provides a mock function with given fields: ctx, limit, offset, withComments
 func (_m *Service) ListPosts(ctx context.Context, limit int32, offset int32, withComments bool) ([]*model.Post, error) {
 	ret := _m.Called(ctx, limit, offset, withComments)
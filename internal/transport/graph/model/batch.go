@@ -0,0 +1,8 @@
+package model
+
+// CommentResult is the outcome of a single comment of a batch, Err is set if it was not created.
+type CommentResult struct {
+	Comment  *Comment
+	Replayed bool
+	Err      error
+}
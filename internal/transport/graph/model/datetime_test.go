@@ -0,0 +1,102 @@
+package model
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestMarshalDateTime(t *testing.T) {
+	tests := []struct {
+		name string
+		time time.Time
+		want string
+	}{
+		{
+			name: "UTC time",
+			time: time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC),
+			want: `"2025-01-02T15:04:05Z"`,
+		},
+		{
+			name: "Time in another zone",
+			time: time.Date(2025, 1, 2, 18, 4, 5, 0, time.FixedZone("MSK", 3*60*60)),
+			want: `"2025-01-02T15:04:05Z"`,
+		},
+		{
+			name: "Fractional seconds are kept",
+			time: time.Date(2025, 1, 2, 15, 4, 5, 999999999, time.UTC),
+			want: `"2025-01-02T15:04:05.999999999Z"`,
+		},
+		{
+			name: "Microseconds of a database timestamp",
+			time: time.Date(2025, 1, 2, 15, 4, 5, 123456000, time.UTC),
+			want: `"2025-01-02T15:04:05.123456Z"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			MarshalDateTime(tt.time).MarshalGQL(&buf)
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("MarshalDateTime() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalDateTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:  "UTC time",
+			value: "2025-01-02T15:04:05Z",
+			want:  time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:  "Time with an offset",
+			value: "2025-01-02T18:04:05+03:00",
+			want:  time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:  "Fractional seconds",
+			value: "2025-01-02T15:04:05.5Z",
+			want:  time.Date(2025, 1, 2, 15, 4, 5, 500000000, time.UTC),
+		},
+		{
+			name:    "Missing offset",
+			value:   "2025-01-02T15:04:05",
+			wantErr: true,
+		},
+		{
+			name:    "Date only",
+			value:   "2025-01-02",
+			wantErr: true,
+		},
+		{
+			name:    "Unix timestamp",
+			value:   int64(1735830245),
+			wantErr: true,
+		},
+		{
+			name:    "Null",
+			value:   nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnmarshalDateTime(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalDateTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("UnmarshalDateTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
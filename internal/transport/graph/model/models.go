@@ -0,0 +1,48 @@
+package model
+
+import (
+	"strconv"
+	"time"
+)
+
+type Post struct {
+	ID             int32      `json:"id"`
+	Title          string     `json:"title"`
+	Content        string     `json:"content"`
+	Author         int32      `json:"author"`
+	AllowComments  bool       `json:"allowComments"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      *time.Time `json:"updatedAt,omitempty"`
+	CommentCount   int32      `json:"commentCount"`
+	LastActivityAt time.Time  `json:"lastActivityAt"`
+	Comments       []*Comment `json:"comments,omitempty"`
+}
+
+func (Post) IsNode() {}
+
+func (Post) IsEntity() {}
+
+func (p Post) GetID() string { return EncodeID(NodeTypePost, p.ID) }
+
+func (p Post) AuthorUser() *User { return &User{ID: strconv.Itoa(int(p.Author))} }
+
+type Comment struct {
+	ID        int32      `json:"id"`
+	PostID    int32      `json:"postId"`
+	ParentID  *int32     `json:"parentId,omitempty"`
+	Depth     int32      `json:"depth"`
+	QuotedID  *int32     `json:"quotedId,omitempty"`
+	Author    int32      `json:"author"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	Replies   []*Comment `json:"replies,omitempty"`
+}
+
+func (Comment) IsNode() {}
+
+func (Comment) IsEntity() {}
+
+func (c Comment) GetID() string { return EncodeID(NodeTypeComment, c.ID) }
+
+func (c Comment) AuthorUser() *User { return &User{ID: strconv.Itoa(int(c.Author))} }
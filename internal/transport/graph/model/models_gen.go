@@ -2,41 +2,56 @@
 
 package model
 
-type Comment struct {
-	ID        int32      `json:"id"`
-	PostID    int32      `json:"postId"`
-	ParentID  *int32     `json:"parentId,omitempty"`
-	Author    int32      `json:"author"`
-	Content   string     `json:"content"`
-	CreatedAt string     `json:"createdAt"`
-	UpdatedAt string     `json:"updatedAt"`
-	Replies   []*Comment `json:"replies,omitempty"`
+// An object with a globally unique, opaque id that can be refetched with the node query
+type Node interface {
+	IsNode()
+	GetID() string
+}
+
+type CommentByIDsInput struct {
+	ID string `json:"ID"`
 }
 
 type CreateCommentInput struct {
-	PostID   int32  `json:"postId"`
-	ParentID *int32 `json:"parentId,omitempty"`
-	Content  string `json:"content"`
+	PostID   string  `json:"postId"`
+	ParentID *string `json:"parentId,omitempty"`
+	Content  string  `json:"content"`
+	// Makes retries safe: the comment is created once per user and id, overrides the Idempotency-Key header
+	ClientMutationID *string `json:"clientMutationId,omitempty"`
+}
+
+type CreateCommentResult struct {
+	Comment *Comment `json:"comment,omitempty"`
+	// Set if the comment was not created
+	Error *ItemError `json:"error,omitempty"`
 }
 
 type CreatePostInput struct {
 	Title         string `json:"title"`
 	Content       string `json:"content"`
 	AllowComments bool   `json:"allowComments"`
+	// Makes retries safe: the post is created once per user and id, overrides the Idempotency-Key header
+	ClientMutationID *string `json:"clientMutationId,omitempty"`
+}
+
+type DeleteResult struct {
+	// The id as it was passed in the mutation
+	ID string `json:"id"`
+	// Set if the item was not deleted
+	Error *ItemError `json:"error,omitempty"`
+}
+
+// Error of a single item of a batch mutation, the code is one of the error codes of the API
+type ItemError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 type Mutation struct {
 }
 
-type Post struct {
-	ID            int32      `json:"id"`
-	Title         string     `json:"title"`
-	Content       string     `json:"content"`
-	Author        int32      `json:"author"`
-	AllowComments bool       `json:"allowComments"`
-	CreatedAt     string     `json:"createdAt"`
-	UpdatedAt     string     `json:"updatedAt"`
-	Comments      []*Comment `json:"comments,omitempty"`
+type PostByIDsInput struct {
+	ID string `json:"ID"`
 }
 
 type Query struct {
@@ -44,3 +59,10 @@ type Query struct {
 
 type Subscription struct {
 }
+
+// A user owned by the accounts service, this subgraph only knows the id, which is the author of posts and comments
+type User struct {
+	ID string `json:"id"`
+}
+
+func (User) IsEntity() {}
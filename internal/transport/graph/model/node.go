@@ -0,0 +1,51 @@
+package model
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+const (
+	NodeTypePost    = "Post"
+	NodeTypeComment = "Comment"
+)
+
+// EncodeID returns the opaque global id of a node, the base64 encoded "Type:id".
+func EncodeID(nodeType string, id int32) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(nodeType + ":" + strconv.FormatInt(int64(id), 10)))
+}
+
+// DecodeID returns the type and the integer id of a global id.
+func DecodeID(globalId string) (string, int32, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(globalId)
+	if err != nil {
+		return "", 0, false
+	}
+
+	nodeType, value, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return "", 0, false
+	}
+
+	id, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || id <= 0 {
+		return "", 0, false
+	}
+
+	return nodeType, int32(id), true
+}
+
+// ParseID accepts either a global id of the given type or a legacy integer id.
+func ParseID(nodeType string, value string) (int32, bool) {
+	if id, err := strconv.ParseInt(value, 10, 32); err == nil {
+		return int32(id), id > 0
+	}
+
+	decodedType, id, ok := DecodeID(value)
+	if !ok || decodedType != nodeType {
+		return 0, false
+	}
+
+	return id, true
+}
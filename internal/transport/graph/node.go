@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/internal/transport/graph/model"
+
+	"go.uber.org/zap"
+)
+
+// parseID accepts either a global id of the given node type or a legacy integer id.
+func parseID(nodeType string, id string) (int32, error) {
+	value, ok := model.ParseID(nodeType, id)
+	if !ok {
+		return 0, repository.ErrInvalidId
+	}
+
+	return value, nil
+}
+
+// loadNode returns the node with the given global id, or nil if it does not exist.
+func (r *Resolver) loadNode(ctx context.Context, id string) (model.Node, error) {
+	nodeType, value, ok := model.DecodeID(id)
+	if !ok {
+		return nil, repository.ErrInvalidId
+	}
+
+	var (
+		node model.Node
+		err  error
+	)
+
+	switch nodeType {
+	case model.NodeTypePost:
+		node, err = r.service.GetPostById(ctx, value, false)
+	case model.NodeTypeComment:
+		node, err = r.service.GetCommentById(ctx, value)
+	default:
+		return nil, repository.ErrInvalidId
+	}
+
+	var domainErr *repository.Error
+	if errors.As(err, &domainErr) && domainErr.Code == repository.CodeNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		r.logs.Error(ctx, "failed to load node", zap.String("err", err.Error()))
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// loadNodes returns the nodes with the given global ids in their order with one lookup per node type,
+// a node that does not exist is nil.
+func (r *Resolver) loadNodes(ctx context.Context, ids []string) ([]model.Node, error) {
+	nodes := make([]model.Node, len(ids))
+
+	var (
+		postIds, commentIds             []int32
+		postPositions, commentPositions []int
+	)
+
+	for i, id := range ids {
+		nodeType, value, ok := model.DecodeID(id)
+		if !ok {
+			return nil, repository.ErrInvalidId
+		}
+
+		switch nodeType {
+		case model.NodeTypePost:
+			postIds = append(postIds, value)
+			postPositions = append(postPositions, i)
+		case model.NodeTypeComment:
+			commentIds = append(commentIds, value)
+			commentPositions = append(commentPositions, i)
+		default:
+			return nil, repository.ErrInvalidId
+		}
+	}
+
+	if len(postIds) > 0 {
+		posts, err := r.service.GetPostsByIds(ctx, postIds)
+		if err != nil {
+			r.logs.Error(ctx, "failed to load post nodes", zap.String("err", err.Error()))
+			return nil, err
+		}
+
+		for i, post := range posts {
+			if post != nil {
+				nodes[postPositions[i]] = post
+			}
+		}
+	}
+
+	if len(commentIds) > 0 {
+		comments, err := r.service.GetCommentsByIds(ctx, commentIds)
+		if err != nil {
+			r.logs.Error(ctx, "failed to load comment nodes", zap.String("err", err.Error()))
+			return nil, err
+		}
+
+		for i, comment := range comments {
+			if comment != nil {
+				nodes[commentPositions[i]] = comment
+			}
+		}
+	}
+
+	return nodes, nil
+}
@@ -0,0 +1,138 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/internal/transport/graph/mocks"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"ozon-tesk-task/pkg/logger"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		want    int32
+		wantErr bool
+	}{
+		{
+			name: "Legacy id",
+			id:   "42",
+			want: 42,
+		},
+		{
+			name: "Global id",
+			id:   model.EncodeID(model.NodeTypePost, 42),
+			want: 42,
+		},
+		{
+			name:    "Global id of another type",
+			id:      model.EncodeID(model.NodeTypeComment, 42),
+			wantErr: true,
+		},
+		{
+			name:    "Negative legacy id",
+			id:      "-1",
+			wantErr: true,
+		},
+		{
+			name:    "Garbage",
+			id:      "not an id",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseID(model.NodeTypePost, tt.id)
+			if tt.wantErr {
+				if !errors.Is(err, repository.ErrInvalidId) {
+					t.Errorf("parseID() error = %v, want %v", err, repository.ErrInvalidId)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parseID() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_queryResolver_Nodes(t *testing.T) {
+	post := &model.Post{ID: 1, Title: "post"}
+	comment := &model.Comment{ID: 2, PostID: 1}
+	other := &model.Post{ID: 3, Title: "other post"}
+
+	tests := []struct {
+		name        string
+		ids         []string
+		serviceMock func(s *mocks.Service)
+		want        []model.Node
+		wantErr     bool
+	}{
+		{
+			name: "Posts and comments in one lookup per type",
+			ids:  []string{post.GetID(), comment.GetID(), other.GetID()},
+			serviceMock: func(s *mocks.Service) {
+				s.On("GetPostsByIds", mock.Anything, []int32{1, 3}).Return([]*model.Post{post, other}, nil).Once()
+				s.On("GetCommentsByIds", mock.Anything, []int32{2}).Return([]*model.Comment{comment}, nil).Once()
+			},
+			want: []model.Node{post, comment, other},
+		},
+		{
+			name: "Missing nodes are null",
+			ids:  []string{model.EncodeID(model.NodeTypeComment, 4), post.GetID(), model.EncodeID(model.NodeTypePost, 5)},
+			serviceMock: func(s *mocks.Service) {
+				s.On("GetPostsByIds", mock.Anything, []int32{1, 5}).Return([]*model.Post{post, nil}, nil)
+				s.On("GetCommentsByIds", mock.Anything, []int32{4}).Return([]*model.Comment{nil}, nil)
+			},
+			want: []model.Node{nil, post, nil},
+		},
+		{
+			name:        "Legacy id is not a global id",
+			ids:         []string{"1"},
+			serviceMock: func(s *mocks.Service) {},
+			wantErr:     true,
+		},
+		{
+			name:        "Unknown type",
+			ids:         []string{model.EncodeID("User", 1)},
+			serviceMock: func(s *mocks.Service) {},
+			wantErr:     true,
+		},
+		{
+			name: "Internal error",
+			ids:  []string{post.GetID()},
+			serviceMock: func(s *mocks.Service) {
+				s.On("GetPostsByIds", mock.Anything, []int32{1}).Return(nil, errors.New("internal error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mocks.NewService(t)
+			log, _ := logger.New("test")
+			p := mocks.NewPubSub(t)
+
+			r := &queryResolver{
+				Resolver: &Resolver{s, log, p},
+			}
+
+			tt.serviceMock(s)
+
+			got, err := r.Nodes(context.Background(), tt.ids)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("queryResolver.Nodes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("queryResolver.Nodes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
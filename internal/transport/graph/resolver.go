@@ -9,19 +9,27 @@ import (
 //go:generate go run github.com/vektra/mockery/v2@latest --name Service
 type Service interface {
 	ListPosts(ctx context.Context, limit, offset int32, withComments bool) ([]*model.Post, error)
-	CreatePost(ctx context.Context, post *model.Post) (*model.Post, error)
+	CreatePost(ctx context.Context, post *model.Post, idempotencyKey string) (*model.Post, error)
 	GetPostById(ctx context.Context, id int32, withComments bool) (*model.Post, error)
-	GetComments(ctx context.Context, postId int32, limit, offset int32) ([]*model.Comment, error)
-	CreateComment(ctx context.Context, comment *model.Comment) (*model.Comment, error)
+	GetPostsByIds(ctx context.Context, ids []int32) ([]*model.Post, error)
+	GetComments(ctx context.Context, postId int32, maxDepth int32, limit, offset int32) ([]*model.Comment, error)
+	GetCommentById(ctx context.Context, commentId int32) (*model.Comment, error)
+	GetCommentsByIds(ctx context.Context, ids []int32) ([]*model.Comment, error)
+	GetCommentThread(ctx context.Context, commentId int32, maxDepth int32) (*model.Comment, error)
+	CreateComment(ctx context.Context, comment *model.Comment, idempotencyKey string) (*model.Comment, bool, error)
 	DeletePost(ctx context.Context, postId int32) error
 	DeleteComment(ctx context.Context, commentId int32) error
+	DeletePosts(ctx context.Context, ids []int32) ([]error, error)
+	DeleteComments(ctx context.Context, ids []int32) ([]error, error)
+	CreateComments(ctx context.Context, comments []*model.Comment, idempotencyKeys []string) ([]*model.CommentResult, error)
 }
 
 //go:generate go run github.com/vektra/mockery/v2@latest --name PubSub
 type PubSub interface {
-	Subscribe(ctx context.Context, postId int32) <-chan *model.Comment
-	Unsubscribe(ctx context.Context, postId int32, ch chan *model.Comment)
+	Subscribe(ctx context.Context, postId int32, since int32) (<-chan *model.Comment, error)
+	Unsubscribe(ctx context.Context, postId int32, ch <-chan *model.Comment)
 	Publish(ctx context.Context, comment *model.Comment)
+	Evict(ctx context.Context, postId int32)
 	Check(postId int32) bool
 }
 
@@ -6,50 +6,16 @@ package graph
 
 import (
 	"context"
-	"errors"
-	"net/http"
 	"ozon-tesk-task/internal/preloads"
 	"ozon-tesk-task/internal/repository"
 	"ozon-tesk-task/internal/transport/graph/model"
 	"ozon-tesk-task/pkg/pointer"
-	"time"
 
-	"github.com/vektah/gqlparser/v2/gqlerror"
 	"go.uber.org/zap"
 )
 
 // CreatePost is the resolver for the createPost field.
 func (r *mutationResolver) CreatePost(ctx context.Context, input model.CreatePostInput) (*model.Post, error) {
-	if input.Title == "" || input.Content == "" {
-		r.logs.Info(ctx, "invalid input arguments")
-		return nil, &gqlerror.Error{
-			Message: "invalid argument",
-			Extensions: map[string]interface{}{
-				"code": http.StatusBadRequest,
-			},
-		}
-	}
-
-	if len(input.Title) > 200 {
-		r.logs.Info(ctx, "input title is too long")
-		return nil, &gqlerror.Error{
-			Message: "title is too long",
-			Extensions: map[string]interface{}{
-				"code": http.StatusBadRequest,
-			},
-		}
-	}
-
-	if len(input.Content) > 2000 {
-		r.logs.Info(ctx, "input content is too long")
-		return nil, &gqlerror.Error{
-			Message: "content is too long",
-			Extensions: map[string]interface{}{
-				"code": http.StatusBadRequest,
-			},
-		}
-	}
-
 	r.logs.Debug(ctx, "Creating post", zap.Any("input", input))
 
 	user := ctx.Value("user_id")
@@ -62,17 +28,11 @@ func (r *mutationResolver) CreatePost(ctx context.Context, input model.CreatePos
 		Title:         input.Title,
 		Content:       input.Content,
 		AllowComments: input.AllowComments,
-		CreatedAt:     time.Now().Format(time.DateTime),
 		Author:        author,
-	})
+	}, idempotencyKey(ctx, input.ClientMutationID))
 	if err != nil {
 		r.logs.Error(ctx, "failed to create post", zap.String("err", err.Error()))
-		return nil, &gqlerror.Error{
-			Message: "failed to create post",
-			Extensions: map[string]interface{}{
-				"code": http.StatusInternalServerError,
-			},
-		}
+		return nil, err
 	}
 
 	return post, nil
@@ -80,24 +40,21 @@ func (r *mutationResolver) CreatePost(ctx context.Context, input model.CreatePos
 
 // CreateComment is the resolver for the createComment field.
 func (r *mutationResolver) CreateComment(ctx context.Context, input model.CreateCommentInput) (*model.Comment, error) {
-	if len(input.Content) > 2000 {
-		r.logs.Info(ctx, "comment is too long")
-		return nil, &gqlerror.Error{
-			Message: "comment is too long",
-			Extensions: map[string]interface{}{
-				"code": http.StatusBadRequest,
-			},
-		}
+	postId, err := parseID(model.NodeTypePost, input.PostID)
+	if err != nil {
+		r.logs.Info(ctx, "invalid input arguments")
+		return nil, err
 	}
 
-	if input.Content == "" || input.PostID <= 0 || pointer.Deref(input.ParentID, 0) < 0 {
-		r.logs.Info(ctx, "invalid input arguments")
-		return nil, &gqlerror.Error{
-			Message: "invalid argument",
-			Extensions: map[string]interface{}{
-				"code": http.StatusBadRequest,
-			},
+	var parentId *int32
+	if input.ParentID != nil {
+		id, err := parseID(model.NodeTypeComment, *input.ParentID)
+		if err != nil {
+			r.logs.Info(ctx, "invalid input arguments")
+			return nil, err
 		}
+
+		parentId = &id
 	}
 
 	r.logs.Debug(ctx, "Creating comment", zap.Any("input", input))
@@ -108,55 +65,142 @@ func (r *mutationResolver) CreateComment(ctx context.Context, input model.Create
 		author = 0
 	}
 
-	comment, err := r.service.CreateComment(ctx, &model.Comment{
-		PostID:    input.PostID,
-		ParentID:  input.ParentID,
-		Content:   input.Content,
-		CreatedAt: time.Now().Format(time.DateTime),
-		Author:    author,
-	})
+	comment, replayed, err := r.service.CreateComment(ctx, &model.Comment{
+		PostID:   postId,
+		ParentID: parentId,
+		Content:  input.Content,
+		Author:   author,
+	}, idempotencyKey(ctx, input.ClientMutationID))
 
 	if err != nil {
-		if errors.Is(err, repository.ErrCommentsNotAllowed) {
-			r.logs.Error(ctx, "can`t create comment", zap.String("err", err.Error()))
-			return nil, &gqlerror.Error{
-				Message: err.Error(),
-				Extensions: map[string]interface{}{
-					"code": http.StatusForbidden,
-				},
-			}
+		r.logs.Error(ctx, "failed to create comment", zap.String("err", err.Error()))
+		return nil, err
+	}
+
+	if replayed {
+		r.logs.Debug(ctx, "Replayed comment for idempotency key", zap.Int32("id", comment.ID))
+		return comment, nil
+	}
+
+	r.pubsub.Publish(ctx, comment)
+
+	return comment, nil
+}
+
+// DeletePosts is the resolver for the deletePosts field.
+func (r *mutationResolver) DeletePosts(ctx context.Context, ids []string) ([]*model.DeleteResult, error) {
+	r.logs.Debug(ctx, "Deleting posts", zap.Strings("ids", ids))
+
+	results, err := deleteBatch(ctx, model.NodeTypePost, ids, func(ctx context.Context, ids []int32) ([]error, error) {
+		errs, err := r.service.DeletePosts(ctx, ids)
+		if err != nil {
+			return nil, err
 		}
-		if errors.Is(err, repository.ErrWrongCommentId) {
-			r.logs.Error(ctx, "can`t create comment", zap.String("err", err.Error()))
-			return nil, &gqlerror.Error{
-				Message: err.Error(),
-				Extensions: map[string]interface{}{
-					"code": http.StatusBadRequest,
-				},
+
+		for i, err := range errs {
+			if err == nil {
+				r.pubsub.Evict(ctx, ids[i])
 			}
 		}
-		if errors.Is(err, repository.ErrMatchCommentWithPost) {
-			r.logs.Error(ctx, "can`t create comment", zap.String("err", err.Error()))
-			return nil, &gqlerror.Error{
-				Message: err.Error(),
-				Extensions: map[string]interface{}{
-					"code": http.StatusBadRequest,
-				},
+
+		return errs, nil
+	})
+	if err != nil {
+		r.logs.Error(ctx, "failed to delete posts", zap.String("err", err.Error()))
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// DeleteComments is the resolver for the deleteComments field.
+func (r *mutationResolver) DeleteComments(ctx context.Context, ids []string) ([]*model.DeleteResult, error) {
+	r.logs.Debug(ctx, "Deleting comments", zap.Strings("ids", ids))
+
+	results, err := deleteBatch(ctx, model.NodeTypeComment, ids, r.service.DeleteComments)
+	if err != nil {
+		r.logs.Error(ctx, "failed to delete comments", zap.String("err", err.Error()))
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// CreateComments is the resolver for the createComments field.
+func (r *mutationResolver) CreateComments(ctx context.Context, inputs []*model.CreateCommentInput) ([]*model.CreateCommentResult, error) {
+	r.logs.Debug(ctx, "Creating comments", zap.Int("count", len(inputs)))
+
+	user := ctx.Value("user_id")
+	author, ok := user.(int32)
+	if !ok {
+		author = 0
+	}
+
+	results := make([]*model.CreateCommentResult, len(inputs))
+
+	var (
+		comments  []*model.Comment
+		keys      []string
+		positions []int
+	)
+
+	for i, input := range inputs {
+		postId, err := parseID(model.NodeTypePost, input.PostID)
+		if err != nil {
+			results[i] = &model.CreateCommentResult{Error: itemError(err)}
+			continue
+		}
+
+		var parentId *int32
+		if input.ParentID != nil {
+			id, err := parseID(model.NodeTypeComment, *input.ParentID)
+			if err != nil {
+				results[i] = &model.CreateCommentResult{Error: itemError(err)}
+				continue
 			}
+
+			parentId = &id
 		}
 
-		r.logs.Error(ctx, "failed to create comment", zap.String("err", err.Error()))
-		return nil, &gqlerror.Error{
-			Message: "failed to create comment",
-			Extensions: map[string]interface{}{
-				"code": http.StatusInternalServerError,
-			},
+		comments = append(comments, &model.Comment{
+			PostID:   postId,
+			ParentID: parentId,
+			Content:  input.Content,
+			Author:   author,
+		})
+		keys = append(keys, pointer.Deref(input.ClientMutationID, ""))
+		positions = append(positions, i)
+	}
+
+	created, err := r.service.CreateComments(ctx, comments, keys)
+	if err != nil {
+		r.logs.Error(ctx, "failed to create comments", zap.String("err", err.Error()))
+		return nil, err
+	}
+
+	for i, result := range created {
+		results[positions[i]] = &model.CreateCommentResult{Comment: result.Comment, Error: itemError(result.Err)}
+
+		if result.Err == nil && !result.Replayed {
+			r.pubsub.Publish(ctx, result.Comment)
 		}
 	}
 
-	r.pubsub.Publish(ctx, comment)
+	return results, nil
+}
 
-	return comment, nil
+// Node is the resolver for the node field.
+func (r *queryResolver) Node(ctx context.Context, id string) (model.Node, error) {
+	r.logs.Debug(ctx, "Loading node", zap.String("id", id))
+
+	return r.loadNode(ctx, id)
+}
+
+// Nodes is the resolver for the nodes field.
+func (r *queryResolver) Nodes(ctx context.Context, ids []string) ([]model.Node, error) {
+	r.logs.Debug(ctx, "Loading nodes", zap.Strings("ids", ids))
+
+	return r.loadNodes(ctx, ids)
 }
 
 // Posts is the resolver for the posts field.
@@ -192,37 +236,18 @@ func (r *queryResolver) Posts(ctx context.Context, page *int32, limit *int32) ([
 
 	posts, err := r.service.ListPosts(ctx, lim, offset, withComments)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			r.logs.Error(ctx, "can`t list posts", zap.String("err", err.Error()))
-			return nil, &gqlerror.Error{
-				Message: err.Error(),
-				Extensions: map[string]interface{}{
-					"code": http.StatusNotFound,
-				},
-			}
-		}
-
 		r.logs.Error(ctx, "failed to list posts", zap.String("err", err.Error()))
-		return nil, &gqlerror.Error{
-			Message: "failed to list posts",
-			Extensions: map[string]interface{}{
-				"code": http.StatusInternalServerError,
-			},
-		}
+		return nil, err
 	}
 
 	return posts, nil
 }
 
 // Post is the resolver for the post field.
-func (r *queryResolver) Post(ctx context.Context, id int32) (*model.Post, error) {
-	if id <= 0 {
-		return nil, &gqlerror.Error{
-			Message: "invalid argument",
-			Extensions: map[string]interface{}{
-				"code": http.StatusBadRequest,
-			},
-		}
+func (r *queryResolver) Post(ctx context.Context, id string) (*model.Post, error) {
+	postId, err := parseID(model.NodeTypePost, id)
+	if err != nil {
+		return nil, err
 	}
 
 	var (
@@ -238,34 +263,24 @@ func (r *queryResolver) Post(ctx context.Context, id int32) (*model.Post, error)
 		}
 	}
 
-	r.logs.Debug(ctx, "Loading post", zap.Int32("id", id), zap.Bool("with comments", withComments))
+	r.logs.Debug(ctx, "Loading post", zap.Int32("id", postId), zap.Bool("with comments", withComments))
 
-	post, err := r.service.GetPostById(ctx, id, withComments)
+	post, err := r.service.GetPostById(ctx, postId, withComments)
 	if err != nil {
-		if errors.Is(err, repository.ErrWrongPostId) {
-			r.logs.Error(ctx, "can`t get post", zap.String("err", err.Error()))
-			return nil, &gqlerror.Error{
-				Message: err.Error(),
-				Extensions: map[string]interface{}{
-					"code": http.StatusNotFound,
-				},
-			}
-		}
-
 		r.logs.Error(ctx, "failed to get post", zap.String("err", err.Error()))
-		return nil, &gqlerror.Error{
-			Message: "failed to get post",
-			Extensions: map[string]interface{}{
-				"code": http.StatusInternalServerError,
-			},
-		}
+		return nil, err
 	}
 
 	return post, nil
 }
 
 // Comments is the resolver for the comments field.
-func (r *queryResolver) Comments(ctx context.Context, postID int32, page *int32, limit *int32) ([]*model.Comment, error) {
+func (r *queryResolver) Comments(ctx context.Context, postID string, page *int32, limit *int32, maxDepth *int32) ([]*model.Comment, error) {
+	postId, err := parseID(model.NodeTypePost, postID)
+	if err != nil {
+		return nil, err
+	}
+
 	lim := pointer.Deref(limit, 10)
 	p := pointer.Deref(page, 1)
 
@@ -280,127 +295,109 @@ func (r *queryResolver) Comments(ctx context.Context, postID int32, page *int32,
 
 	offset := lim * (p - 1)
 
-	r.logs.Debug(ctx, "Loading comments", zap.Int32("post", postID), zap.Int32("page", p))
+	r.logs.Debug(ctx, "Loading comments", zap.Int32("post", postId), zap.Int32("page", p))
 
-	comments, err := r.service.GetComments(ctx, postID, lim, offset)
-	if err != nil {
-		if errors.Is(err, repository.ErrWrongPostId) {
-			r.logs.Error(ctx, "can`t get post", zap.String("err", err.Error()))
-			return nil, &gqlerror.Error{
-				Message: err.Error(),
-				Extensions: map[string]interface{}{
-					"code": http.StatusNotFound,
-				},
-			}
-		}
+	if pointer.Deref(maxDepth, 0) < 0 {
+		return nil, repository.ErrInvalidArgument
+	}
 
+	comments, err := r.service.GetComments(ctx, postId, pointer.Deref(maxDepth, repository.UnlimitedDepth), lim, offset)
+	if err != nil {
 		r.logs.Error(ctx, "failed to list comments", zap.String("err", err.Error()))
-		return nil, &gqlerror.Error{
-			Message: "failed to get post",
-			Extensions: map[string]interface{}{
-				"code": http.StatusInternalServerError,
-			},
-		}
+		return nil, err
 	}
 
 	return comments, nil
 }
 
-// DeletePost is the resolver for the deletePost field.
-func (r *queryResolver) DeletePost(ctx context.Context, postID int32) (int32, error) {
-	if postID <= 0 {
-		return 0, &gqlerror.Error{
-			Message: "invalid argument",
-			Extensions: map[string]interface{}{
-				"code": http.StatusBadRequest,
-			},
-		}
+// CommentThread is the resolver for the commentThread field.
+func (r *queryResolver) CommentThread(ctx context.Context, id string, maxDepth *int32) (*model.Comment, error) {
+	commentId, err := parseID(model.NodeTypeComment, id)
+	if err != nil || pointer.Deref(maxDepth, 0) < 0 {
+		return nil, repository.ErrInvalidArgument
 	}
 
-	r.logs.Debug(ctx, "Deleting post", zap.Int32("id", postID))
+	r.logs.Debug(ctx, "Loading comment thread", zap.Int32("id", commentId), zap.Int32("max depth", pointer.Deref(maxDepth, repository.UnlimitedDepth)))
 
-	err := r.service.DeletePost(ctx, postID)
+	thread, err := r.service.GetCommentThread(ctx, commentId, pointer.Deref(maxDepth, repository.UnlimitedDepth))
 	if err != nil {
-		if errors.Is(err, repository.ErrWrongPostId) {
-			r.logs.Error(ctx, "can`t get post", zap.String("err", err.Error()))
-			return 0, &gqlerror.Error{
-				Message: err.Error(),
-				Extensions: map[string]interface{}{
-					"code": http.StatusNotFound,
-				},
-			}
-		}
+		r.logs.Error(ctx, "failed to get comment thread", zap.String("err", err.Error()))
+		return nil, err
+	}
 
+	return thread, nil
+}
+
+// DeletePost is the resolver for the deletePost field.
+func (r *queryResolver) DeletePost(ctx context.Context, postID string) (int32, error) {
+	postId, err := parseID(model.NodeTypePost, postID)
+	if err != nil {
+		return 0, err
+	}
+
+	r.logs.Debug(ctx, "Deleting post", zap.Int32("id", postId))
+
+	err = r.service.DeletePost(ctx, postId)
+	if err != nil {
 		r.logs.Error(ctx, "failed to delete post", zap.String("err", err.Error()))
-		return 0, &gqlerror.Error{
-			Message: "failed to delete post",
-			Extensions: map[string]interface{}{
-				"code": http.StatusInternalServerError,
-			},
-		}
+		return 0, err
 	}
 
-	return postID, nil
+	r.pubsub.Evict(ctx, postId)
+
+	return postId, nil
 }
 
 // DeleteComment is the resolver for the deleteComment field.
-func (r *queryResolver) DeleteComment(ctx context.Context, commentID int32) (int32, error) {
-	if commentID <= 0 {
-		return 0, &gqlerror.Error{
-			Message: "invalid argument",
-			Extensions: map[string]interface{}{
-				"code": http.StatusBadRequest,
-			},
-		}
+func (r *queryResolver) DeleteComment(ctx context.Context, commentID string) (int32, error) {
+	commentId, err := parseID(model.NodeTypeComment, commentID)
+	if err != nil {
+		return 0, err
 	}
 
-	r.logs.Debug(ctx, "Deleting comment", zap.Int32("id", commentID))
+	r.logs.Debug(ctx, "Deleting comment", zap.Int32("id", commentId))
 
-	err := r.service.DeleteComment(ctx, commentID)
+	err = r.service.DeleteComment(ctx, commentId)
 	if err != nil {
-		if errors.Is(err, repository.ErrWrongCommentId) {
-			r.logs.Error(ctx, "can`t get comment", zap.String("err", err.Error()))
-			return 0, &gqlerror.Error{
-				Message: err.Error(),
-				Extensions: map[string]interface{}{
-					"code": http.StatusNotFound,
-				},
-			}
-		}
-
 		r.logs.Error(ctx, "failed to delete comment", zap.String("err", err.Error()))
-		return 0, &gqlerror.Error{
-			Message: "failed to delete post",
-			Extensions: map[string]interface{}{
-				"code": http.StatusInternalServerError,
-			},
-		}
+		return 0, err
 	}
 
-	return commentID, nil
+	return commentId, nil
 }
 
 // CommentAdded is the resolver for the commentAdded field.
-func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID int32) (<-chan *model.Comment, error) {
-	if postID <= 0 {
-		return nil, &gqlerror.Error{
-			Message: "invalid argument",
-			Extensions: map[string]interface{}{
-				"code": http.StatusBadRequest,
-			},
+func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID string, since *string) (<-chan *model.Comment, error) {
+	postId, err := parseID(model.NodeTypePost, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	var sinceId int32
+	if since != nil {
+		if sinceId, err = parseID(model.NodeTypeComment, *since); err != nil {
+			return nil, err
 		}
 	}
 
-	if !r.pubsub.Check(postID) {
-		_, err := r.service.GetPostById(ctx, postID, false)
+	if !r.pubsub.Check(postId) {
+		_, err := r.service.GetPostById(ctx, postId, false)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	r.logs.Debug(ctx, "Creating new subscription", zap.Int32("postId", postID))
+	r.logs.Debug(ctx, "Creating new subscription", zap.Int32("postId", postId), zap.Int32("since", sinceId))
+
+	ch, err := r.pubsub.Subscribe(ctx, postId, sinceId)
+	if err != nil {
+		return nil, err
+	}
 
-	ch := r.pubsub.Subscribe(ctx, postID)
+	go func() {
+		<-ctx.Done()
+		r.pubsub.Unsubscribe(ctx, postId, ch)
+	}()
 
 	return ch, nil
 }
@@ -8,12 +8,17 @@ import (
 	"ozon-tesk-task/internal/transport/graph/model"
 	"ozon-tesk-task/pkg/logger"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/mock"
 )
 
+func legacyID(id int32) string {
+	return strconv.Itoa(int(id))
+}
+
 func Test_mutationResolver_CreatePost(t *testing.T) {
 	type (
 		mockServiceBehavior func(s *mocks.Service, post, returnPost *model.Post)
@@ -47,38 +52,10 @@ func Test_mutationResolver_CreatePost(t *testing.T) {
 				AllowComments: true,
 			},
 			serviceMock: func(s *mocks.Service, post *model.Post, returnPost *model.Post) {
-				s.On("CreatePost", mock.Anything, post).Return(returnPost, nil)
+				s.On("CreatePost", mock.Anything, post, "").Return(returnPost, nil)
 			},
 			wantErr: false,
 		},
-		{
-			name: "Empty title",
-			args: args{
-				ctx: context.Background(),
-				input: model.CreatePostInput{
-					Title:         "",
-					Content:       "content",
-					AllowComments: true,
-				},
-			},
-			want:        nil,
-			serviceMock: func(s *mocks.Service, post *model.Post, returnPost *model.Post) {},
-			wantErr:     true,
-		},
-		{
-			name: "Empty сontent",
-			args: args{
-				ctx: context.Background(),
-				input: model.CreatePostInput{
-					Title:         "title",
-					Content:       "",
-					AllowComments: true,
-				},
-			},
-			want:        nil,
-			serviceMock: func(s *mocks.Service, post *model.Post, returnPost *model.Post) {},
-			wantErr:     true,
-		},
 		{
 			name: "Internal Error",
 			args: args{
@@ -91,38 +68,10 @@ func Test_mutationResolver_CreatePost(t *testing.T) {
 			},
 			want: nil,
 			serviceMock: func(s *mocks.Service, post *model.Post, returnPost *model.Post) {
-				s.On("CreatePost", mock.Anything, post).Return(nil, errors.New("internal error"))
+				s.On("CreatePost", mock.Anything, post, "").Return(nil, errors.New("internal error"))
 			},
 			wantErr: true,
 		},
-		{
-			name: "Long content",
-			args: args{
-				ctx: context.Background(),
-				input: model.CreatePostInput{
-					Title:         "title",
-					Content:       string(make([]byte, 2001)),
-					AllowComments: true,
-				},
-			},
-			want:        nil,
-			serviceMock: func(s *mocks.Service, post *model.Post, returnPost *model.Post) {},
-			wantErr:     true,
-		},
-		{
-			name: "Long title",
-			args: args{
-				ctx: context.Background(),
-				input: model.CreatePostInput{
-					Title:         string(make([]byte, 201)),
-					Content:       "content",
-					AllowComments: true,
-				},
-			},
-			want:        nil,
-			serviceMock: func(s *mocks.Service, post *model.Post, returnPost *model.Post) {},
-			wantErr:     true,
-		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -138,7 +87,6 @@ func Test_mutationResolver_CreatePost(t *testing.T) {
 				Title:         tt.args.input.Title,
 				Content:       tt.args.input.Content,
 				AllowComments: tt.args.input.AllowComments,
-				CreatedAt:     time.Now().Format(time.DateTime),
 				Author:        0,
 			}, tt.want)
 
@@ -177,12 +125,12 @@ func Test_mutationResolver_CreateComment(t *testing.T) {
 			args: args{
 				ctx: context.Background(),
 				input: model.CreateCommentInput{
-					PostID:  1,
+					PostID:  "1",
 					Content: "content",
 				},
 			},
 			mockService: func(s *mocks.Service, comment, returnComment *model.Comment) {
-				s.On("CreateComment", mock.Anything, comment).Return(returnComment, nil)
+				s.On("CreateComment", mock.Anything, comment, "").Return(returnComment, false, nil)
 			},
 			mockPubSub: func(p *mocks.PubSub, comment *model.Comment) {
 				p.On("Publish", mock.Anything, comment)
@@ -199,13 +147,13 @@ func Test_mutationResolver_CreateComment(t *testing.T) {
 			args: args{
 				ctx: context.Background(),
 				input: model.CreateCommentInput{
-					PostID:   1,
-					ParentID: func() *int32 { v := int32(1); return &v }(),
+					PostID:   model.EncodeID(model.NodeTypePost, 1),
+					ParentID: func() *string { v := model.EncodeID(model.NodeTypeComment, 1); return &v }(),
 					Content:  "content",
 				},
 			},
 			mockService: func(s *mocks.Service, comment, returnComment *model.Comment) {
-				s.On("CreateComment", mock.Anything, comment).Return(returnComment, nil)
+				s.On("CreateComment", mock.Anything, comment, "").Return(returnComment, false, nil)
 			},
 			mockPubSub: func(p *mocks.PubSub, comment *model.Comment) {
 				p.On("Publish", mock.Anything, comment)
@@ -222,7 +170,7 @@ func Test_mutationResolver_CreateComment(t *testing.T) {
 			args: args{
 				ctx: context.Background(),
 				input: model.CreateCommentInput{
-					PostID:  -1,
+					PostID:  "-1",
 					Content: "content",
 				},
 			},
@@ -232,12 +180,33 @@ func Test_mutationResolver_CreateComment(t *testing.T) {
 			wantErr:     true,
 		},
 		{
-			name: "Long content",
+			name: "Replayed comment is not published again",
 			args: args{
 				ctx: context.Background(),
 				input: model.CreateCommentInput{
-					PostID:  1,
-					Content: string(make([]byte, 2001)),
+					PostID:           "1",
+					Content:          "content",
+					ClientMutationID: func() *string { v := "retry-1"; return &v }(),
+				},
+			},
+			mockService: func(s *mocks.Service, comment, returnComment *model.Comment) {
+				s.On("CreateComment", mock.Anything, comment, "retry-1").Return(returnComment, true, nil)
+			},
+			mockPubSub: func(p *mocks.PubSub, comment *model.Comment) {},
+			want: &model.Comment{
+				ID:      1,
+				PostID:  1,
+				Content: "content",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Global id of a comment as post id",
+			args: args{
+				ctx: context.Background(),
+				input: model.CreateCommentInput{
+					PostID:  model.EncodeID(model.NodeTypeComment, 1),
+					Content: "content",
 				},
 			},
 			mockService: func(s *mocks.Service, comment, returnComment *model.Comment) {},
@@ -246,12 +215,12 @@ func Test_mutationResolver_CreateComment(t *testing.T) {
 			wantErr:     true,
 		},
 		{
-			name: "Invalid post id",
+			name: "Invalid parent id",
 			args: args{
 				ctx: context.Background(),
 				input: model.CreateCommentInput{
-					PostID:   1,
-					ParentID: func() *int32 { v := int32(-1); return &v }(),
+					PostID:   "1",
+					ParentID: func() *string { v := "-1"; return &v }(),
 					Content:  "content",
 				},
 			},
@@ -265,12 +234,12 @@ func Test_mutationResolver_CreateComment(t *testing.T) {
 			args: args{
 				ctx: context.Background(),
 				input: model.CreateCommentInput{
-					PostID:  1232131212,
+					PostID:  "1232131212",
 					Content: "content",
 				},
 			},
 			mockService: func(s *mocks.Service, comment, returnComment *model.Comment) {
-				s.On("CreateComment", mock.Anything, comment).Return(nil, repository.ErrWrongPostId)
+				s.On("CreateComment", mock.Anything, comment, "").Return(nil, false, repository.ErrWrongPostId)
 			},
 			mockPubSub: func(p *mocks.PubSub, comment *model.Comment) {},
 			want:       nil,
@@ -281,13 +250,13 @@ func Test_mutationResolver_CreateComment(t *testing.T) {
 			args: args{
 				ctx: context.Background(),
 				input: model.CreateCommentInput{
-					PostID:   1,
-					ParentID: func() *int32 { v := int32(1233231); return &v }(),
+					PostID:   "1",
+					ParentID: func() *string { v := "1233231"; return &v }(),
 					Content:  "content",
 				},
 			},
 			mockService: func(s *mocks.Service, comment, returnComment *model.Comment) {
-				s.On("CreateComment", mock.Anything, comment).Return(nil, repository.ErrWrongCommentId)
+				s.On("CreateComment", mock.Anything, comment, "").Return(nil, false, repository.ErrWrongCommentId)
 			},
 			mockPubSub: func(p *mocks.PubSub, comment *model.Comment) {},
 			want:       nil,
@@ -298,13 +267,13 @@ func Test_mutationResolver_CreateComment(t *testing.T) {
 			args: args{
 				ctx: context.Background(),
 				input: model.CreateCommentInput{
-					PostID:   1,
-					ParentID: func() *int32 { v := int32(5); return &v }(),
+					PostID:   "1",
+					ParentID: func() *string { v := "5"; return &v }(),
 					Content:  "content",
 				},
 			},
 			mockService: func(s *mocks.Service, comment, returnComment *model.Comment) {
-				s.On("CreateComment", mock.Anything, comment).Return(nil, repository.ErrMatchCommentWithPost)
+				s.On("CreateComment", mock.Anything, comment, "").Return(nil, false, repository.ErrMatchCommentWithPost)
 			},
 			mockPubSub: func(p *mocks.PubSub, comment *model.Comment) {},
 			want:       nil,
@@ -315,13 +284,13 @@ func Test_mutationResolver_CreateComment(t *testing.T) {
 			args: args{
 				ctx: context.Background(),
 				input: model.CreateCommentInput{
-					PostID:   1,
-					ParentID: func() *int32 { v := int32(1); return &v }(),
+					PostID:   "1",
+					ParentID: func() *string { v := "1"; return &v }(),
 					Content:  "content",
 				},
 			},
 			mockService: func(s *mocks.Service, comment, returnComment *model.Comment) {
-				s.On("CreateComment", mock.Anything, comment).Return(nil, repository.ErrCommentsNotAllowed)
+				s.On("CreateComment", mock.Anything, comment, "").Return(nil, false, repository.ErrCommentsNotAllowed)
 			},
 			mockPubSub: func(p *mocks.PubSub, comment *model.Comment) {},
 			want:       nil,
@@ -332,13 +301,13 @@ func Test_mutationResolver_CreateComment(t *testing.T) {
 			args: args{
 				ctx: context.Background(),
 				input: model.CreateCommentInput{
-					PostID:   1,
-					ParentID: func() *int32 { v := int32(1); return &v }(),
+					PostID:   "1",
+					ParentID: func() *string { v := "1"; return &v }(),
 					Content:  "content",
 				},
 			},
 			mockService: func(s *mocks.Service, comment, returnComment *model.Comment) {
-				s.On("CreateComment", mock.Anything, comment).Return(nil, errors.New("internal error"))
+				s.On("CreateComment", mock.Anything, comment, "").Return(nil, false, errors.New("internal error"))
 			},
 			mockPubSub: func(p *mocks.PubSub, comment *model.Comment) {},
 			want:       nil,
@@ -355,12 +324,19 @@ func Test_mutationResolver_CreateComment(t *testing.T) {
 				Resolver: &Resolver{s, log, p},
 			}
 
+			postId, _ := model.ParseID(model.NodeTypePost, tt.args.input.PostID)
+
+			var parentId *int32
+			if tt.args.input.ParentID != nil {
+				id, _ := model.ParseID(model.NodeTypeComment, *tt.args.input.ParentID)
+				parentId = &id
+			}
+
 			tt.mockService(s, &model.Comment{
-				PostID:    tt.args.input.PostID,
-				ParentID:  tt.args.input.ParentID,
-				Content:   tt.args.input.Content,
-				CreatedAt: time.Now().Format(time.DateTime),
-				Author:    0,
+				PostID:   postId,
+				ParentID: parentId,
+				Content:  tt.args.input.Content,
+				Author:   0,
 			}, tt.want)
 
 			tt.mockPubSub(p, tt.want)
@@ -647,7 +623,7 @@ func Test_queryResolver_Post(t *testing.T) {
 
 			tt.serviceMock(s, tt.args.id, tt.want)
 
-			got, err := r.Post(tt.args.ctx, tt.args.id)
+			got, err := r.Post(tt.args.ctx, legacyID(tt.args.id))
 			if (err != nil) != tt.wantErr {
 				t.Errorf("queryResolver.Post() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -661,7 +637,7 @@ func Test_queryResolver_Post(t *testing.T) {
 
 func Test_queryResolver_DeletePost(t *testing.T) {
 	type (
-		mockServiceBehavior func(s *mocks.Service, postID int32)
+		mockServiceBehavior func(s *mocks.Service, p *mocks.PubSub, postID int32)
 		args                struct {
 			ctx    context.Context
 			postID int32
@@ -681,8 +657,9 @@ func Test_queryResolver_DeletePost(t *testing.T) {
 				ctx:    context.Background(),
 				postID: 1,
 			},
-			serviceMock: func(s *mocks.Service, postID int32) {
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub, postID int32) {
 				s.On("DeletePost", mock.Anything, postID).Return(nil)
+				p.On("Evict", mock.Anything, postID).Return()
 			},
 			want:    1,
 			wantErr: false,
@@ -693,7 +670,7 @@ func Test_queryResolver_DeletePost(t *testing.T) {
 				ctx:    context.Background(),
 				postID: -1,
 			},
-			serviceMock: func(s *mocks.Service, postID int32) {},
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub, postID int32) {},
 			want:        0,
 			wantErr:     true,
 		},
@@ -703,7 +680,7 @@ func Test_queryResolver_DeletePost(t *testing.T) {
 				ctx:    context.Background(),
 				postID: 3123213,
 			},
-			serviceMock: func(s *mocks.Service, postID int32) {
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub, postID int32) {
 				s.On("DeletePost", mock.Anything, postID).Return(repository.ErrWrongPostId)
 			},
 			want:    0,
@@ -715,7 +692,7 @@ func Test_queryResolver_DeletePost(t *testing.T) {
 				ctx:    context.Background(),
 				postID: 1,
 			},
-			serviceMock: func(s *mocks.Service, postID int32) {
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub, postID int32) {
 				s.On("DeletePost", mock.Anything, postID).Return(errors.New("internal error"))
 			},
 			want:    0,
@@ -732,9 +709,9 @@ func Test_queryResolver_DeletePost(t *testing.T) {
 				Resolver: &Resolver{s, log, p},
 			}
 
-			tt.serviceMock(s, tt.args.postID)
+			tt.serviceMock(s, p, tt.args.postID)
 
-			got, err := r.DeletePost(tt.args.ctx, tt.args.postID)
+			got, err := r.DeletePost(tt.args.ctx, legacyID(tt.args.postID))
 			if (err != nil) != tt.wantErr {
 				t.Errorf("queryResolver.DeletePost() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -753,6 +730,7 @@ func Test_subscriptionResolver_CommentAdded(t *testing.T) {
 		args                struct {
 			ctx    context.Context
 			postID int32
+			since  *int32
 		}
 	)
 
@@ -800,7 +778,7 @@ func Test_subscriptionResolver_CommentAdded(t *testing.T) {
 			},
 			pubsubMock: func(p *mocks.PubSub, postId int32) {
 				p.On("Check", postId).Return(false)
-				p.On("Subscribe", mock.Anything, postId).Return(ch)
+				p.On("Subscribe", mock.Anything, postId, int32(0)).Return(ch, nil)
 			},
 			serviceMock: func(s *mocks.Service, postID int32) {
 				s.On("GetPostById", mock.Anything, postID, mock.Anything).Return(&model.Post{}, nil)
@@ -816,12 +794,42 @@ func Test_subscriptionResolver_CommentAdded(t *testing.T) {
 			},
 			pubsubMock: func(p *mocks.PubSub, postId int32) {
 				p.On("Check", postId).Return(true)
-				p.On("Subscribe", mock.Anything, postId).Return(ch)
+				p.On("Subscribe", mock.Anything, postId, int32(0)).Return(ch, nil)
 			},
 			serviceMock: func(s *mocks.Service, postID int32) {},
 			want:        ch,
 			wantErr:     false,
 		},
+		{
+			name: "Resume from last seen comment",
+			args: args{
+				ctx:    context.Background(),
+				postID: 1,
+				since:  func() *int32 { v := int32(5); return &v }(),
+			},
+			pubsubMock: func(p *mocks.PubSub, postId int32) {
+				p.On("Check", postId).Return(true)
+				p.On("Subscribe", mock.Anything, postId, int32(5)).Return(ch, nil)
+			},
+			serviceMock: func(s *mocks.Service, postID int32) {},
+			want:        ch,
+			wantErr:     false,
+		},
+		{
+			name: "Missed comments can't be loaded",
+			args: args{
+				ctx:    context.Background(),
+				postID: 1,
+				since:  func() *int32 { v := int32(5); return &v }(),
+			},
+			pubsubMock: func(p *mocks.PubSub, postId int32) {
+				p.On("Check", postId).Return(true)
+				p.On("Subscribe", mock.Anything, postId, int32(5)).Return(nil, errors.New("database is down"))
+			},
+			serviceMock: func(s *mocks.Service, postID int32) {},
+			want:        nil,
+			wantErr:     true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -836,7 +844,13 @@ func Test_subscriptionResolver_CommentAdded(t *testing.T) {
 			tt.serviceMock(s, tt.args.postID)
 			tt.pubsubMock(p, tt.args.postID)
 
-			got, err := r.CommentAdded(tt.args.ctx, tt.args.postID)
+			var since *string
+			if tt.args.since != nil {
+				id := legacyID(*tt.args.since)
+				since = &id
+			}
+
+			got, err := r.CommentAdded(tt.args.ctx, legacyID(tt.args.postID), since)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("subscriptionResolver.CommentAdded() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -847,3 +861,36 @@ func Test_subscriptionResolver_CommentAdded(t *testing.T) {
 		})
 	}
 }
+
+func Test_subscriptionResolver_CommentAdded_Unsubscribe(t *testing.T) {
+	s := mocks.NewService(t)
+	log, _ := logger.New("test")
+	p := mocks.NewPubSub(t)
+
+	r := &subscriptionResolver{
+		Resolver: &Resolver{s, log, p},
+	}
+
+	ch := make(chan *model.Comment, 1)
+	unsubscribed := make(chan struct{})
+
+	p.On("Check", int32(1)).Return(true)
+	p.On("Subscribe", mock.Anything, int32(1), int32(0)).Return((<-chan *model.Comment)(ch), nil)
+	p.On("Unsubscribe", mock.Anything, int32(1), (<-chan *model.Comment)(ch)).
+		Run(func(mock.Arguments) { close(ch); close(unsubscribed) }).
+		Return()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if _, err := r.CommentAdded(ctx, "1", nil); err != nil {
+		t.Fatalf("subscriptionResolver.CommentAdded() error = %v", err)
+	}
+
+	cancel()
+
+	select {
+	case <-unsubscribed:
+	case <-time.After(time.Second):
+		t.Error("subscriptionResolver.CommentAdded() did not unsubscribe after the subscription was closed")
+	}
+}
@@ -0,0 +1,140 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"ozon-tesk-task/internal/transport/grpc/pb"
+	"ozon-tesk-task/internal/transport/http/middleware"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func (s *commentServer) ListComments(ctx context.Context, req *pb.ListCommentsRequest) (*pb.ListCommentsResponse, error) {
+	var v violations
+	page, limit := pagination(&v, req.GetPage(), req.GetLimit())
+	maxDepth := depth(&v, req.MaxDepth)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	s.logs.Debug(ctx, "Loading comments", zap.Int32("post", req.GetPostId()), zap.Int32("page", page))
+
+	comments, err := s.service.GetComments(ctx, req.GetPostId(), maxDepth, limit, limit*(page-1))
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		s.logs.Error(ctx, "failed to list comments", zap.String("err", err.Error()))
+		return nil, toStatus(err)
+	}
+
+	return &pb.ListCommentsResponse{Comments: toComments(comments)}, nil
+}
+
+func (s *commentServer) GetComment(ctx context.Context, req *pb.GetCommentRequest) (*pb.Comment, error) {
+	var v violations
+	maxDepth := depth(&v, req.MaxDepth)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	s.logs.Debug(ctx, "Loading comment thread", zap.Int32("id", req.GetId()), zap.Int32("max depth", maxDepth))
+
+	thread, err := s.service.GetCommentThread(ctx, req.GetId(), maxDepth)
+	if err != nil {
+		s.logs.Error(ctx, "failed to get comment thread", zap.String("err", err.Error()))
+		return nil, toStatus(err)
+	}
+
+	return toComment(thread), nil
+}
+
+func (s *commentServer) CreateComment(ctx context.Context, req *pb.CreateCommentRequest) (*pb.Comment, error) {
+	var v violations
+	s.checkString(&v, "CreateCommentInput", "content", req.GetContent())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	s.logs.Debug(ctx, "Creating comment", zap.Int32("post", req.GetPostId()))
+
+	author, _ := ctx.Value(middleware.UserIDKey).(int32)
+
+	comment, replayed, err := s.service.CreateComment(ctx, &model.Comment{
+		PostID:   req.GetPostId(),
+		ParentID: req.ParentId,
+		Content:  req.GetContent(),
+		Author:   author,
+	}, req.GetIdempotencyKey())
+	if err != nil {
+		s.logs.Error(ctx, "failed to create comment", zap.String("err", err.Error()))
+		return nil, toStatus(err)
+	}
+
+	if replayed {
+		s.logs.Debug(ctx, "Replayed comment for idempotency key", zap.Int32("id", comment.ID))
+	} else {
+		s.ps.Publish(ctx, comment)
+	}
+
+	return toComment(comment), nil
+}
+
+func (s *commentServer) DeleteComment(ctx context.Context, req *pb.DeleteCommentRequest) (*emptypb.Empty, error) {
+	s.logs.Debug(ctx, "Deleting comment", zap.Int32("id", req.GetId()))
+
+	if err := s.service.DeleteComment(ctx, req.GetId()); err != nil {
+		s.logs.Error(ctx, "failed to delete comment", zap.String("err", err.Error()))
+		return nil, toStatus(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *commentServer) WatchComments(req *pb.WatchCommentsRequest, stream grpc.ServerStreamingServer[pb.Comment]) error {
+	ctx := stream.Context()
+	postId := req.GetPostId()
+
+	if !s.ps.Check(postId) {
+		if _, err := s.service.GetPostById(ctx, postId, false); err != nil {
+			return toStatus(err)
+		}
+	}
+
+	s.logs.Debug(ctx, "Creating new subscription", zap.Int32("postId", postId), zap.Int32("since", req.GetSinceId()))
+
+	ch, err := s.ps.Subscribe(ctx, postId, req.GetSinceId())
+	if err != nil {
+		return toStatus(err)
+	}
+	defer s.ps.Unsubscribe(ctx, postId, ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case comment, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			if err := stream.Send(toComment(comment)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// depth returns the maximum depth of replies, unlimited if not set.
+func depth(v *violations, maxDepth *int32) int32 {
+	if maxDepth == nil {
+		return repository.UnlimitedDepth
+	}
+
+	if *maxDepth < 0 {
+		v.add("max_depth", "must not be negative")
+	}
+
+	return *maxDepth
+}
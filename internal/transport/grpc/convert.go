@@ -0,0 +1,128 @@
+package grpc
+
+import (
+	"errors"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"ozon-tesk-task/internal/transport/grpc/pb"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const errorDomain = "posts.v1"
+
+var statusCodes = map[repository.Code]codes.Code{
+	repository.CodeNotFound:        codes.NotFound,
+	repository.CodeValidation:      codes.InvalidArgument,
+	repository.CodeForbidden:       codes.PermissionDenied,
+	repository.CodeConflict:        codes.FailedPrecondition,
+	repository.CodeCommentsLocked:  codes.FailedPrecondition,
+	repository.CodeCommentTooDeep:  codes.FailedPrecondition,
+	repository.CodeUnauthenticated: codes.Unauthenticated,
+}
+
+// toStatus reports domain errors with their code in an ErrorInfo detail and hides any other error,
+// the same way the GraphQL error presenter does.
+func toStatus(err error) error {
+	var domainErr *repository.Error
+	if !errors.As(err, &domainErr) {
+		return status.Error(codes.Internal, "internal server error")
+	}
+
+	code, ok := statusCodes[domainErr.Code]
+	if !ok {
+		code = codes.InvalidArgument
+	}
+
+	st, detailsErr := status.New(code, domainErr.Message).WithDetails(&errdetails.ErrorInfo{
+		Reason: string(domainErr.Code),
+		Domain: errorDomain,
+	})
+	if detailsErr != nil {
+		return status.Error(code, domainErr.Message)
+	}
+
+	return st.Err()
+}
+
+// violations collects every invalid field of a request, so they are reported at once.
+type violations []*errdetails.BadRequest_FieldViolation
+
+func (v *violations) add(field, description string) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{Field: field, Description: description})
+}
+
+func (v violations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+
+	st, err := status.New(codes.InvalidArgument, "request is invalid").WithDetails(
+		&errdetails.ErrorInfo{Reason: string(repository.CodeValidation), Domain: errorDomain},
+		&errdetails.BadRequest{FieldViolations: v},
+	)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "request is invalid")
+	}
+
+	return st.Err()
+}
+
+func toPost(post *model.Post) *pb.Post {
+	if post == nil {
+		return nil
+	}
+
+	return &pb.Post{
+		Id:             post.ID,
+		Title:          post.Title,
+		Content:        post.Content,
+		Author:         post.Author,
+		AllowComments:  post.AllowComments,
+		CreatedAt:      timestamppb.New(post.CreatedAt),
+		UpdatedAt:      toTimestamp(post.UpdatedAt),
+		CommentCount:   post.CommentCount,
+		LastActivityAt: timestamppb.New(post.LastActivityAt),
+		Comments:       toComments(post.Comments),
+	}
+}
+
+func toComment(comment *model.Comment) *pb.Comment {
+	if comment == nil {
+		return nil
+	}
+
+	return &pb.Comment{
+		Id:        comment.ID,
+		PostId:    comment.PostID,
+		ParentId:  comment.ParentID,
+		Depth:     comment.Depth,
+		QuotedId:  comment.QuotedID,
+		Author:    comment.Author,
+		Content:   comment.Content,
+		CreatedAt: timestamppb.New(comment.CreatedAt),
+		UpdatedAt: toTimestamp(comment.UpdatedAt),
+		Replies:   toComments(comment.Replies),
+	}
+}
+
+func toComments(comments []*model.Comment) []*pb.Comment {
+	result := make([]*pb.Comment, 0, len(comments))
+	for _, comment := range comments {
+		result = append(result, toComment(comment))
+	}
+
+	return result
+}
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+
+	return timestamppb.New(*t)
+}
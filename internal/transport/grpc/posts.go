@@ -0,0 +1,115 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"ozon-tesk-task/internal/transport/grpc/pb"
+	"ozon-tesk-task/internal/transport/http/middleware"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const defaultLimit = 10
+
+func (s *postServer) ListPosts(ctx context.Context, req *pb.ListPostsRequest) (*pb.ListPostsResponse, error) {
+	var v violations
+	page, limit := pagination(&v, req.GetPage(), req.GetLimit())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	s.logs.Debug(ctx, "Loading posts", zap.Bool("with comments", req.GetWithComments()), zap.Int32("page", page))
+
+	posts, err := s.service.ListPosts(ctx, limit, limit*(page-1), req.GetWithComments())
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		s.logs.Error(ctx, "failed to list posts", zap.String("err", err.Error()))
+		return nil, toStatus(err)
+	}
+
+	response := &pb.ListPostsResponse{Posts: make([]*pb.Post, 0, len(posts))}
+	for _, post := range posts {
+		response.Posts = append(response.Posts, toPost(post))
+	}
+
+	return response, nil
+}
+
+func (s *postServer) GetPost(ctx context.Context, req *pb.GetPostRequest) (*pb.Post, error) {
+	s.logs.Debug(ctx, "Loading post", zap.Int32("id", req.GetId()), zap.Bool("with comments", req.GetWithComments()))
+
+	post, err := s.service.GetPostById(ctx, req.GetId(), req.GetWithComments())
+	if err != nil {
+		s.logs.Error(ctx, "failed to get post", zap.String("err", err.Error()))
+		return nil, toStatus(err)
+	}
+
+	return toPost(post), nil
+}
+
+func (s *postServer) CreatePost(ctx context.Context, req *pb.CreatePostRequest) (*pb.Post, error) {
+	var v violations
+	s.checkString(&v, "CreatePostInput", "title", req.GetTitle())
+	s.checkString(&v, "CreatePostInput", "content", req.GetContent())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	s.logs.Debug(ctx, "Creating post", zap.String("title", req.GetTitle()))
+
+	author, _ := ctx.Value(middleware.UserIDKey).(int32)
+
+	post, err := s.service.CreatePost(ctx, &model.Post{
+		Title:         req.GetTitle(),
+		Content:       req.GetContent(),
+		AllowComments: req.GetAllowComments(),
+		Author:        author,
+	}, req.GetIdempotencyKey())
+	if err != nil {
+		s.logs.Error(ctx, "failed to create post", zap.String("err", err.Error()))
+		return nil, toStatus(err)
+	}
+
+	return toPost(post), nil
+}
+
+func (s *postServer) DeletePost(ctx context.Context, req *pb.DeletePostRequest) (*emptypb.Empty, error) {
+	s.logs.Debug(ctx, "Deleting post", zap.Int32("id", req.GetId()))
+
+	if err := s.service.DeletePost(ctx, req.GetId()); err != nil {
+		s.logs.Error(ctx, "failed to delete post", zap.String("err", err.Error()))
+		return nil, toStatus(err)
+	}
+
+	s.ps.Evict(ctx, req.GetId())
+
+	return &emptypb.Empty{}, nil
+}
+
+// pagination treats unset fields as the first page of the default size.
+func pagination(v *violations, page, limit int32) (int32, int32) {
+	if page < 0 {
+		v.add("page", "must not be negative")
+	}
+	if limit < 0 {
+		v.add("limit", "must not be negative")
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+
+	return page, limit
+}
+
+// checkString validates a string field against the @constraint directive of the matching GraphQL input field.
+func (h *Handler) checkString(v *violations, input, field, value string) {
+	if message := h.validator.Check(input+"."+field, value); message != "" {
+		v.add(field, message)
+	}
+}
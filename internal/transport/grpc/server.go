@@ -0,0 +1,189 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"ozon-tesk-task/internal/auth"
+	"ozon-tesk-task/internal/config"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/internal/transport/graph"
+	"ozon-tesk-task/internal/transport/grpc/pb"
+	"ozon-tesk-task/internal/transport/http/middleware"
+	"ozon-tesk-task/pkg/logger"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+)
+
+const (
+	requestIDKey     = "x-request-id"
+	userAgentKey     = "user-agent"
+	authorizationKey = "authorization"
+)
+
+type Handler struct {
+	cfg       *config.Config
+	service   graph.Service
+	logs      logger.Logger
+	ps        graph.PubSub
+	validator *graph.InputValidator
+}
+
+// Server is the gRPC server that reports its services as not serving when it is being stopped.
+type Server struct {
+	*grpc.Server
+	health *health.Server
+}
+
+type postServer struct {
+	pb.UnimplementedPostServiceServer
+	*Handler
+}
+
+type commentServer struct {
+	pb.UnimplementedCommentServiceServer
+	*Handler
+}
+
+// NewServer creates the gRPC server with the post and comment services, the health service and reflection.
+func NewServer(cfg *config.Config, service graph.Service, ps graph.PubSub, logs logger.Logger, opts ...grpc.ServerOption) (*Server, error) {
+	validator, err := graph.NewInputValidator(graph.NewExecutableSchema(graph.Config{}).Schema(), cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := &Handler{
+		cfg:       cfg,
+		service:   service,
+		logs:      logs,
+		ps:        ps,
+		validator: validator,
+	}
+
+	identity := withIdentity(auth.NewVerifier(cfg.TokenSecret))
+
+	srv := grpc.NewServer(append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, err := identity(ctx)
+			if err != nil {
+				return nil, toStatus(err)
+			}
+
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := identity(ss.Context())
+			if err != nil {
+				return toStatus(err)
+			}
+
+			return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		}),
+	}, opts...)...)
+
+	pb.RegisterPostServiceServer(srv, &postServer{Handler: handler})
+	pb.RegisterCommentServiceServer(srv, &commentServer{Handler: handler})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(pb.PostService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(pb.CommentService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, healthServer)
+
+	reflection.Register(srv)
+
+	return &Server{Server: srv, health: healthServer}, nil
+}
+
+// GracefulStop tells the health watchers that the services are not serving and waits for the running calls.
+func (s *Server) GracefulStop() {
+	s.health.Shutdown()
+	s.Server.GracefulStop()
+}
+
+// Stop tells the health watchers that the services are not serving and cancels the running calls.
+func (s *Server) Stop() {
+	s.health.Shutdown()
+	s.Server.Stop()
+}
+
+// withIdentity identifies the caller the same way as the HTTP transports do: by the bearer token of the
+// authorization metadata if there is one, by the user agent otherwise.
+func withIdentity(verifier *auth.Verifier) func(ctx context.Context) (context.Context, error) {
+	return func(ctx context.Context) (context.Context, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		var requestID, user, token string
+		if values := md.Get(requestIDKey); len(values) > 0 {
+			requestID = values[0]
+		}
+		if values := md.Get(userAgentKey); len(values) > 0 {
+			user = values[0]
+		}
+		if values := md.Get(authorizationKey); len(values) > 0 {
+			token = values[0]
+		}
+
+		if requestID == "" {
+			newUUID, err := uuid.NewUUID()
+			if err == nil {
+				requestID = newUUID.String()
+			}
+		}
+		if user == "" {
+			user = "unknown"
+		}
+
+		agentId := middleware.UserID(user)
+
+		ctx = context.WithValue(ctx, logger.RequestID, requestID)
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			addr := p.Addr.String()
+			ctx = auth.WithConnection(ctx, addr)
+
+			// the port changes with every connection, a retry over a new one comes from the same host
+			if host, _, err := net.SplitHostPort(addr); err == nil {
+				addr = host
+			}
+			ctx = auth.WithClient(ctx, addr, agentId)
+		}
+
+		if token == "" {
+			return context.WithValue(ctx, middleware.UserIDKey, agentId), nil
+		}
+
+		userId, err := verifier.Verify(token)
+		if err != nil {
+			return nil, repository.ErrUnauthenticated
+		}
+
+		ctx = context.WithValue(ctx, middleware.UserIDKey, userId)
+
+		return auth.WithUser(ctx, userId), nil
+	}
+}
+
+// WithContext returns the options that derive the context of every call with fn, e.g. to start a database session.
+func WithContext(fn func(ctx context.Context) context.Context) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			return handler(fn(ctx), req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, &contextStream{ServerStream: ss, ctx: fn(ss.Context())})
+		}),
+	}
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,386 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"ozon-tesk-task/internal/auth"
+	"ozon-tesk-task/internal/auth/authtest"
+	"ozon-tesk-task/internal/config"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/internal/transport/graph"
+	"ozon-tesk-task/internal/transport/graph/mocks"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"ozon-tesk-task/internal/transport/grpc/pb"
+	"ozon-tesk-task/internal/transport/http/middleware"
+	"ozon-tesk-task/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestClient(t *testing.T, service graph.Service, ps graph.PubSub, opts ...grpc.ServerOption) *grpc.ClientConn {
+	t.Helper()
+
+	log, _ := logger.New("test")
+
+	srv, err := NewServer(&config.Config{}, service, ps, log, opts...)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	t.Cleanup(srv.Stop)
+
+	return dial(t, srv)
+}
+
+// dial serves the server on an in-memory listener and connects to it.
+func dial(t *testing.T, srv *Server) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	go srv.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// errorReason returns the reason of the ErrorInfo detail and the fields of the BadRequest detail.
+func errorReason(err error) (string, []string) {
+	var (
+		reason string
+		fields []string
+	)
+
+	for _, detail := range status.Convert(err).Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			reason = d.GetReason()
+		case *errdetails.BadRequest:
+			for _, violation := range d.GetFieldViolations() {
+				fields = append(fields, violation.GetField())
+			}
+		}
+	}
+
+	return reason, fields
+}
+
+func TestPostServer(t *testing.T) {
+	post := &model.Post{ID: 1, Title: "title", Content: "content", AllowComments: true, CreatedAt: time.Now()}
+
+	tests := []struct {
+		name        string
+		call        func(client pb.PostServiceClient) error
+		serviceMock func(s *mocks.Service)
+		wantCode    codes.Code
+		wantReason  string
+		wantFields  []string
+	}{
+		{
+			name: "Create post",
+			call: func(client pb.PostServiceClient) error {
+				_, err := client.CreatePost(context.Background(), &pb.CreatePostRequest{Title: "title", Content: "content", AllowComments: true, IdempotencyKey: "key"})
+				return err
+			},
+			serviceMock: func(s *mocks.Service) {
+				s.On("CreatePost", mock.Anything, mock.MatchedBy(func(p *model.Post) bool {
+					return p.Title == "title" && p.Author != 0
+				}), "key").Return(post, nil)
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "Create post with invalid fields",
+			call: func(client pb.PostServiceClient) error {
+				_, err := client.CreatePost(context.Background(), &pb.CreatePostRequest{})
+				return err
+			},
+			serviceMock: func(s *mocks.Service) {},
+			wantCode:    codes.InvalidArgument,
+			wantReason:  string(repository.CodeValidation),
+			wantFields:  []string{"title", "content"},
+		},
+		{
+			name: "Missing post",
+			call: func(client pb.PostServiceClient) error {
+				_, err := client.GetPost(context.Background(), &pb.GetPostRequest{Id: 5})
+				return err
+			},
+			serviceMock: func(s *mocks.Service) {
+				s.On("GetPostById", mock.Anything, int32(5), false).Return(nil, repository.ErrWrongPostId)
+			},
+			wantCode:   codes.NotFound,
+			wantReason: string(repository.CodeNotFound),
+		},
+		{
+			name: "Empty page",
+			call: func(client pb.PostServiceClient) error {
+				response, err := client.ListPosts(context.Background(), &pb.ListPostsRequest{Page: 3})
+				if err == nil && len(response.GetPosts()) != 0 {
+					return errors.New("page is not empty")
+				}
+				return err
+			},
+			serviceMock: func(s *mocks.Service) {
+				s.On("ListPosts", mock.Anything, int32(10), int32(20), false).Return(nil, repository.ErrNotFound)
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "Negative limit",
+			call: func(client pb.PostServiceClient) error {
+				_, err := client.ListPosts(context.Background(), &pb.ListPostsRequest{Limit: -1})
+				return err
+			},
+			serviceMock: func(s *mocks.Service) {},
+			wantCode:    codes.InvalidArgument,
+			wantReason:  string(repository.CodeValidation),
+			wantFields:  []string{"limit"},
+		},
+		{
+			name: "Internal error",
+			call: func(client pb.PostServiceClient) error {
+				_, err := client.DeletePost(context.Background(), &pb.DeletePostRequest{Id: 1})
+				return err
+			},
+			serviceMock: func(s *mocks.Service) {
+				s.On("DeletePost", mock.Anything, int32(1)).Return(errors.New("connection refused"))
+			},
+			wantCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mocks.NewService(t)
+			tt.serviceMock(s)
+
+			client := pb.NewPostServiceClient(newTestClient(t, s, mocks.NewPubSub(t)))
+
+			err := tt.call(client)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("code = %v, want %v, error %v", got, tt.wantCode, err)
+			}
+
+			reason, fields := errorReason(err)
+			if reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", reason, tt.wantReason)
+			}
+			if len(fields) != len(tt.wantFields) {
+				t.Errorf("invalid fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestCommentServer_CreateComment(t *testing.T) {
+	comment := &model.Comment{ID: 2, PostID: 1, Content: "comment"}
+
+	tests := []struct {
+		name     string
+		replayed bool
+		publish  bool
+	}{
+		{
+			name:    "New comment is published",
+			publish: true,
+		},
+		{
+			name:     "Replayed comment is not published again",
+			replayed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mocks.NewService(t)
+			p := mocks.NewPubSub(t)
+
+			s.On("CreateComment", mock.Anything, mock.MatchedBy(func(c *model.Comment) bool {
+				return c.PostID == 1 && c.ParentID != nil && *c.ParentID == 3
+			}), "key").Return(comment, tt.replayed, nil)
+			if tt.publish {
+				p.On("Publish", mock.Anything, comment).Return()
+			}
+
+			client := pb.NewCommentServiceClient(newTestClient(t, s, p))
+
+			parentId := int32(3)
+			got, err := client.CreateComment(context.Background(), &pb.CreateCommentRequest{
+				PostId:         1,
+				ParentId:       &parentId,
+				Content:        "comment",
+				IdempotencyKey: "key",
+			})
+			if err != nil {
+				t.Fatalf("CreateComment() error = %v", err)
+			}
+			if got.GetId() != comment.ID {
+				t.Errorf("CreateComment() id = %d, want %d", got.GetId(), comment.ID)
+			}
+		})
+	}
+}
+
+func TestCommentServer_WatchComments(t *testing.T) {
+	s := mocks.NewService(t)
+	p := mocks.NewPubSub(t)
+
+	ch := make(chan *model.Comment, 1)
+	subscribed := make(chan struct{})
+	unsubscribed := make(chan struct{})
+
+	p.On("Check", int32(1)).Return(false)
+	s.On("GetPostById", mock.Anything, int32(1), false).Return(&model.Post{ID: 1}, nil)
+	p.On("Subscribe", mock.Anything, int32(1), int32(0)).
+		Run(func(mock.Arguments) { close(subscribed) }).
+		Return((<-chan *model.Comment)(ch), nil)
+	p.On("Unsubscribe", mock.Anything, int32(1), (<-chan *model.Comment)(ch)).
+		Run(func(mock.Arguments) { close(ch); close(unsubscribed) }).
+		Return()
+
+	client := pb.NewCommentServiceClient(newTestClient(t, s, p))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	streamCtx, closeStream := context.WithCancel(ctx)
+	stream, err := client.WatchComments(streamCtx, &pb.WatchCommentsRequest{PostId: 1})
+	if err != nil {
+		t.Fatalf("WatchComments() error = %v", err)
+	}
+
+	select {
+	case <-subscribed:
+	case <-ctx.Done():
+		t.Fatal("WatchComments() did not subscribe")
+	}
+
+	ch <- &model.Comment{ID: 7, PostID: 1, Content: "comment"}
+
+	got, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if got.GetId() != 7 {
+		t.Errorf("Recv() id = %d, want 7", got.GetId())
+	}
+
+	closeStream()
+
+	select {
+	case <-unsubscribed:
+	case <-ctx.Done():
+		t.Fatal("WatchComments() did not unsubscribe after the stream was closed")
+	}
+}
+
+func TestServer_Authorization(t *testing.T) {
+	const secret = "secret"
+
+	s := mocks.NewService(t)
+	s.On("GetPostById", mock.MatchedBy(func(ctx context.Context) bool {
+		userId, ok := auth.User(ctx)
+		return ok && userId == 42 && ctx.Value(middleware.UserIDKey) == int32(42)
+	}), int32(1), false).Return(&model.Post{ID: 1}, nil).Once()
+
+	log, _ := logger.New("test")
+
+	cfg := &config.Config{}
+	cfg.TokenSecret = secret
+
+	srv, err := NewServer(cfg, s, mocks.NewPubSub(t), log)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	t.Cleanup(srv.Stop)
+
+	client := pb.NewPostServiceClient(dial(t, srv))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authorizationKey, "Bearer "+authtest.Sign(secret, 42, time.Hour))
+	if _, err := client.GetPost(ctx, &pb.GetPostRequest{Id: 1}); err != nil {
+		t.Errorf("GetPost() with a valid token error = %v", err)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), authorizationKey, "Bearer "+authtest.Sign("other", 42, time.Hour))
+	_, err = client.GetPost(ctx, &pb.GetPostRequest{Id: 1})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("GetPost() with an invalid token code = %v, want %v", status.Code(err), codes.Unauthenticated)
+	}
+	if reason, _ := errorReason(err); reason != string(repository.CodeUnauthenticated) {
+		t.Errorf("GetPost() with an invalid token reason = %q", reason)
+	}
+}
+
+func TestServer_GracefulStop(t *testing.T) {
+	log, _ := logger.New("test")
+
+	srv, err := NewServer(&config.Config{}, mocks.NewService(t), mocks.NewPubSub(t), log)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	conn := dial(t, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	watch, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{Service: pb.CommentService_ServiceDesc.ServiceName})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	health, err := watch.Recv()
+	if err != nil || health.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("health = %v, %v, want SERVING", health.GetStatus(), err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	health, err = watch.Recv()
+	if err != nil || health.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("health = %v, %v, want NOT_SERVING", health.GetStatus(), err)
+	}
+
+	// The watch is the last running call.
+	cancel()
+	<-stopped
+}
+
+func TestNewServer_WithContext(t *testing.T) {
+	type sessionKey struct{}
+
+	s := mocks.NewService(t)
+	s.On("GetPostById", mock.MatchedBy(func(ctx context.Context) bool {
+		return ctx.Value(sessionKey{}) != nil && ctx.Value(middleware.UserIDKey) != nil
+	}), int32(1), false).Return(&model.Post{ID: 1}, nil)
+
+	conn := newTestClient(t, s, mocks.NewPubSub(t), WithContext(func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, sessionKey{}, true)
+	})...)
+
+	if _, err := pb.NewPostServiceClient(conn).GetPost(context.Background(), &pb.GetPostRequest{Id: 1}); err != nil {
+		t.Errorf("GetPost() error = %v", err)
+	}
+}
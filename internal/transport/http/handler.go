@@ -1,59 +1,173 @@
 package http
 
 import (
-	"ozon-tesk-task/internal/pubsub"
+	"context"
+	"fmt"
+	"net/http"
+	"ozon-tesk-task/internal/auth"
+	"ozon-tesk-task/internal/config"
+	"ozon-tesk-task/internal/persisted"
 	"ozon-tesk-task/internal/transport/graph"
 	"ozon-tesk-task/internal/transport/http/middleware"
 	"ozon-tesk-task/pkg/logger"
+	"strings"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
 	"github.com/99designs/gqlgen/graphql/handler/lru"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/gorilla/websocket"
 	"github.com/labstack/echo"
 	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.uber.org/zap"
+)
+
+const (
+	acceptHeader     = "Accept"
+	eventStreamMedia = "text/event-stream"
+
+	graphqlWsSubprotocol          = "graphql-ws"
+	graphqlTransportWsSubprotocol = "graphql-transport-ws"
 )
 
 type Handler struct {
-	service graph.Service
-	logs    logger.Logger
-	ps      graph.PubSub
+	cfg      *config.Config
+	service  graph.Service
+	logs     logger.Logger
+	ps       graph.PubSub
+	verifier *auth.Verifier
 }
 
-func NewHandler(e *echo.Echo, service graph.Service, logs logger.Logger) {
+func NewHandler(e *echo.Echo, cfg *config.Config, service graph.Service, ps graph.PubSub, logs logger.Logger) error {
 	handler := &Handler{
-		service: service,
-		logs:    logs,
-		ps:      pubsub.New(),
+		cfg:      cfg,
+		service:  service,
+		logs:     logs,
+		ps:       ps,
+		verifier: auth.NewVerifier(cfg.TokenSecret),
+	}
+
+	proxies, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	graphqlHandler, err := handler.graphqlHandler()
+	if err != nil {
+		return err
 	}
 
-	e.POST("/query", handler.graphqlHandler())
-	e.GET("/query", handler.graphqlHandler())
+	identity := middleware.IdentityMiddleware(handler.verifier, proxies, rejectGraphQL)
+	connectionLimit := middleware.ConnectionLimitMiddleware(cfg.MaxConnectionsPerIP, proxies)
+
+	e.POST("/query", graphqlHandler, identity, connectionLimit)
+	e.GET("/query", graphqlHandler, identity, connectionLimit)
 	e.GET("/", handler.playgroundHandler())
+
+	return nil
 }
 
-func (h *Handler) graphqlHandler() echo.HandlerFunc {
-	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: graph.NewResolver(h.service, h.logs, h.ps)}))
+func (h *Handler) graphqlHandler() (echo.HandlerFunc, error) {
+	schema := graph.NewExecutableSchema(graph.Config{
+		Resolvers:  graph.NewResolver(h.service, h.logs, h.ps),
+		Complexity: graph.NewComplexity(h.cfg.ListComplexityWeight),
+	})
+
+	constraints, err := graph.ValidateConstraints(schema.Schema(), h.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := handler.New(schema)
 
-	srv.AddTransport(transport.Websocket{})
+	srv.AddTransport(h.websocketTransport())
+	srv.AddTransport(transport.SSE{
+		KeepAlivePingInterval: h.cfg.SSEKeepAliveInterval,
+	})
 
 	srv.AddTransport(transport.Options{})
 	srv.AddTransport(transport.GET{})
 	srv.AddTransport(transport.POST{})
 
+	srv.SetErrorPresenter(graph.ErrorPresenter)
+
 	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
 
 	srv.Use(extension.Introspection{})
-	srv.Use(extension.AutomaticPersistedQuery{
-		Cache: lru.New[string](100),
-	})
+	if h.cfg.PersistedQueryManifest != "" {
+		manifest, err := persisted.LoadManifest(h.cfg.PersistedQueryManifest)
+		if err != nil {
+			return nil, err
+		}
+		if err := manifest.Validate(schema.Schema()); err != nil {
+			return nil, fmt.Errorf("persisted query manifest does not match the schema: %w", err)
+		}
 
+		srv.Use(middleware.PersistedQueryAllowlist{Queries: manifest.Queries()})
+	} else {
+		srv.Use(extension.AutomaticPersistedQuery{
+			Cache: lru.New[string](100),
+		})
+	}
+	if h.cfg.MaxQueryComplexity > 0 {
+		srv.Use(extension.FixedComplexityLimit(h.cfg.MaxQueryComplexity))
+	}
+	srv.Use(middleware.DepthLimit{MaxDepth: h.cfg.MaxQueryDepth})
+
+	srv.AroundFields(constraints)
 	srv.AroundOperations(middleware.LogMiddleware(h.logs))
+	srv.AroundOperations(middleware.SubscriptionLimitMiddleware(h.cfg.MaxSubscriptionsPerUser))
 
 	return func(c echo.Context) error {
-		srv.ServeHTTP(c.Response().Writer, c.Request())
+		if strings.Contains(c.Request().Header.Get(acceptHeader), eventStreamMedia) {
+			c.Response().Header().Set("X-Accel-Buffering", "no")
+		}
+
+		srv.ServeHTTP(c.Response(), c.Request())
 		return nil
+	}, nil
+}
+
+// rejectGraphQL answers the requests that are not allowed to reach the schema with a GraphQL error response.
+func rejectGraphQL(c echo.Context, err error) error {
+	return c.JSON(http.StatusUnauthorized, graphql.Response{
+		Errors: gqlerror.List{graph.ErrorPresenter(c.Request().Context(), err)},
+	})
+}
+
+func (h *Handler) websocketTransport() transport.Websocket {
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{graphqlTransportWsSubprotocol, graphqlWsSubprotocol},
+	}
+
+	if h.cfg.AllowedOrigins != "" {
+		origins := make(map[string]struct{})
+		for _, origin := range strings.Split(h.cfg.AllowedOrigins, ",") {
+			origins[strings.TrimSpace(origin)] = struct{}{}
+		}
+
+		upgrader.CheckOrigin = func(r *http.Request) bool {
+			if _, ok := origins["*"]; ok {
+				return true
+			}
+
+			_, ok := origins[r.Header.Get("Origin")]
+			return ok
+		}
+	}
+
+	return transport.Websocket{
+		Upgrader:              upgrader,
+		InitFunc:              middleware.WebsocketInit(h.verifier, h.cfg.RequireAuth),
+		InitTimeout:           h.cfg.InitTimeout,
+		KeepAlivePingInterval: h.cfg.KeepAliveInterval,
+		PingPongInterval:      h.cfg.PingPongInterval,
+		ErrorFunc: func(ctx context.Context, err error) {
+			h.logs.Warn(ctx, "websocket error", zap.String("err", err.Error()))
+		},
 	}
 }
 
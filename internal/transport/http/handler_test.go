@@ -0,0 +1,101 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"ozon-tesk-task/internal/config"
+	"ozon-tesk-task/internal/transport/graph/mocks"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"ozon-tesk-task/pkg/logger"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_SSESubscription(t *testing.T) {
+	comments := make(chan *model.Comment)
+
+	ps := mocks.NewPubSub(t)
+	ps.On("Check", int32(1)).Return(true)
+	ps.On("Subscribe", mock.Anything, int32(1), int32(0)).Return((<-chan *model.Comment)(comments), nil)
+	ps.On("Unsubscribe", mock.Anything, int32(1), mock.Anything).Return().Maybe()
+
+	log, _ := logger.New("test")
+
+	cfg := &config.Config{
+		ValidationConfig: config.ValidationConfig{PostTitleMaxLength: 100, PostContentMaxLength: 2000, CommentMaxLength: 2000},
+		WebsocketConfig:  config.WebsocketConfig{MaxConnectionsPerIP: 10},
+	}
+
+	e := echo.New()
+	if err := NewHandler(e, cfg, mocks.NewService(t), ps, log); err != nil {
+		t.Fatalf("NewHandler() error = %v", err)
+	}
+
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	body := `{"query": "subscription { commentAdded(postId: 1) { content } }"}`
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/query", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(acceptHeader, eventStreamMedia)
+
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), eventStreamMedia) {
+		t.Fatalf("response status = %d, content type = %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if resp.Header.Get("X-Accel-Buffering") != "no" {
+		t.Errorf("X-Accel-Buffering = %q, want no", resp.Header.Get("X-Accel-Buffering"))
+	}
+
+	events := bufio.NewReader(resp.Body)
+
+	// the event is read while the subscription is still open, so it must have been flushed
+	comments <- &model.Comment{ID: 2, PostID: 1, Content: "comment"}
+
+	event, data := readEvent(t, events)
+	if event != "next" || !strings.Contains(data, `"content":"comment"`) {
+		t.Errorf("first event = %q %s, want the comment", event, data)
+	}
+
+	close(comments)
+
+	if event, _ := readEvent(t, events); event != "complete" {
+		t.Errorf("last event = %q, want complete", event)
+	}
+}
+
+// readEvent reads the next event of the stream and skips the keep-alive comments.
+func readEvent(t *testing.T, r *bufio.Reader) (event string, data string) {
+	t.Helper()
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		case line == "" && event != "":
+			return event, data
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+var depthSchema = gqlparser.MustLoadSchema(&ast.Source{Name: "schema.graphqls", Input: `
+type Query {
+	post(id: ID!): Post
+}
+
+interface Node {
+	id: ID!
+}
+
+type Post implements Node {
+	id: ID!
+	comments: [Comment!]!
+}
+
+type Comment implements Node {
+	id: ID!
+	replies: [Comment!]!
+}
+`})
+
+func TestDepthLimit_MutateOperationContext(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		operation string
+		wantErr   bool
+	}{
+		{
+			name:  "At the limit",
+			query: `{ post(id: 1) { comments { replies { id } } } }`,
+		},
+		{
+			name:    "Over the limit",
+			query:   `{ post(id: 1) { comments { replies { replies { id } } } } }`,
+			wantErr: true,
+		},
+		{
+			name: "Fragment at the limit",
+			query: `
+				query { post(id: 1) { ...comments } }
+				fragment comments on Post { comments { replies { id } } }`,
+		},
+		{
+			name: "Fragment over the limit",
+			query: `
+				query { post(id: 1) { ...comments } }
+				fragment comments on Post { comments { replies { replies { id } } } }`,
+			wantErr: true,
+		},
+		{
+			name:  "Inline fragment at the limit",
+			query: `{ post(id: 1) { ... on Node { ... on Post { comments { replies { id } } } } } }`,
+		},
+		{
+			name:    "Inline fragment over the limit",
+			query:   `{ post(id: 1) { ... on Post { comments { ... on Comment { replies { replies { id } } } } } } }`,
+			wantErr: true,
+		},
+		{
+			name:  "Introspection is not counted",
+			query: `{ __schema { types { fields { type { ofType { ofType { name } } } } } } }`,
+		},
+		{
+			name: "Only the executed operation is counted",
+			query: `
+				query Shallow { post(id: 1) { id } }
+				query Deep { post(id: 1) { comments { replies { replies { id } } } } }`,
+			operation: "Shallow",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, errs := gqlparser.LoadQuery(depthSchema, tt.query)
+			if errs != nil {
+				t.Fatalf("LoadQuery() error = %v", errs)
+			}
+
+			opCtx := &graphql.OperationContext{
+				Doc:           doc,
+				OperationName: tt.operation,
+				Operation:     doc.Operations.ForName(tt.operation),
+			}
+
+			err := DepthLimit{MaxDepth: 4}.MutateOperationContext(context.Background(), opCtx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DepthLimit.MutateOperationContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && err.Extensions["code"] != errDepthLimit {
+				t.Errorf("DepthLimit.MutateOperationContext() code = %v, want %s", err.Extensions["code"], errDepthLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"ozon-tesk-task/internal/auth"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo"
+)
+
+const authorizationHeader = "Authorization"
+
+// IdentityMiddleware identifies the client of plain HTTP requests the same way LogMiddleware does for GraphQL operations,
+// so both transports attribute posts and comments to the same user. A request with a valid bearer token is sent by the
+// user of the token, a request with an invalid one is answered by reject. Anonymous clients are told apart by their
+// address, as reported by the trusted proxies, and their user agent.
+func IdentityMiddleware(verifier *auth.Verifier, proxies TrustedProxies, reject func(c echo.Context, err error) error) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			requestID := req.Header.Get(requestIDHeader)
+			if requestID == "" {
+				newUUID, err := uuid.NewUUID()
+				if err == nil {
+					requestID = newUUID.String()
+				}
+			}
+
+			user := req.Header.Get(userAgentHeader)
+			if user == "" {
+				user = "unknown"
+			}
+
+			ctx := context.WithValue(req.Context(), logger.RequestID, requestID)
+			ctx = auth.WithConnection(ctx, req.RemoteAddr)
+
+			userId := UserID(user)
+			ctx = auth.WithClient(ctx, proxies.ClientIP(req), userId)
+			if token := req.Header.Get(authorizationHeader); token != "" {
+				id, err := verifier.Verify(token)
+				if err != nil {
+					return reject(c, repository.ErrUnauthenticated)
+				}
+
+				userId = id
+				ctx = auth.WithUser(ctx, id)
+			}
+
+			ctx = context.WithValue(ctx, UserIDKey, userId)
+
+			c.SetRequest(req.WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"ozon-tesk-task/internal/auth"
+	"ozon-tesk-task/internal/auth/authtest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+func TestIdentityMiddleware(t *testing.T) {
+	proxies, err := ParseTrustedProxies("1.1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		token           string
+		forwardedFor    string
+		wantStatus      int
+		wantUser        int32
+		wantScope       string
+		wantClientScope string
+	}{
+		{
+			name:            "Valid token",
+			token:           "Bearer " + authtest.Sign(testSecret, 42, time.Hour),
+			wantStatus:      http.StatusOK,
+			wantUser:        42,
+			wantScope:       "user:42",
+			wantClientScope: "user:42",
+		},
+		{
+			name:       "Invalid token",
+			token:      "Bearer " + authtest.Sign("other", 42, time.Hour),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:            "Anonymous request",
+			wantStatus:      http.StatusOK,
+			wantUser:        UserID("agent"),
+			wantScope:       "connection:1.1.1.1:1000",
+			wantClientScope: fmt.Sprintf("client:1.1.1.1/%d", UserID("agent")),
+		},
+		{
+			name:            "Anonymous request through a trusted proxy",
+			forwardedFor:    "2.2.2.2",
+			wantStatus:      http.StatusOK,
+			wantUser:        UserID("agent"),
+			wantScope:       "connection:1.1.1.1:1000",
+			wantClientScope: fmt.Sprintf("client:2.2.2.2/%d", UserID("agent")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "1.1.1.1:1000"
+			req.Header.Set(userAgentHeader, "agent")
+			if tt.token != "" {
+				req.Header.Set(authorizationHeader, tt.token)
+			}
+			if tt.forwardedFor != "" {
+				req.Header.Set(echo.HeaderXForwardedFor, tt.forwardedFor)
+			}
+			rec := httptest.NewRecorder()
+
+			var (
+				userId      int32
+				scope       string
+				clientScope string
+			)
+			reject := func(c echo.Context, err error) error {
+				return c.String(http.StatusUnauthorized, err.Error())
+			}
+			handler := IdentityMiddleware(auth.NewVerifier(testSecret), proxies, reject)(func(c echo.Context) error {
+				userId, _ = c.Request().Context().Value(UserIDKey).(int32)
+				scope = auth.Scope(c.Request().Context())
+				clientScope = auth.ClientScope(c.Request().Context())
+
+				return c.NoContent(http.StatusOK)
+			})
+
+			if err := handler(echo.New().NewContext(req, rec)); err != nil {
+				t.Fatal(err)
+			}
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if userId != tt.wantUser {
+				t.Errorf("user = %d, want %d", userId, tt.wantUser)
+			}
+			if scope != tt.wantScope {
+				t.Errorf("scope = %q, want %q", scope, tt.wantScope)
+			}
+			if clientScope != tt.wantClientScope {
+				t.Errorf("client scope = %q, want %q", clientScope, tt.wantClientScope)
+			}
+		})
+	}
+}
@@ -14,8 +14,15 @@ import (
 const (
 	requestIDHeader = "X-Request-ID"
 	userAgentHeader = "User-Agent"
+
+	UserIDKey = "user_id"
 )
 
+func UserID(identity string) int32 {
+	hash := sha256.Sum256([]byte(identity))
+	return int32(binary.BigEndian.Uint32(hash[:8]))
+}
+
 func LogMiddleware(log logger.Logger) graphql.OperationMiddleware {
 	return func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
 		opCtx := graphql.GetOperationContext(ctx)
@@ -43,11 +50,14 @@ func LogMiddleware(log logger.Logger) graphql.OperationMiddleware {
 
 		ctx = context.WithValue(ctx, logger.RequestID, req)
 
-		if user != "" {
-			hash := sha256.Sum256([]byte(user))
-			userId = int32(binary.BigEndian.Uint32(hash[:8]))
+		if id, ok := ctx.Value(UserIDKey).(int32); ok {
+			userId = id
+		} else {
+			if user != "" {
+				userId = UserID(user)
+			}
+			ctx = context.WithValue(ctx, UserIDKey, userId)
 		}
-		ctx = context.WithValue(ctx, "user_id", userId)
 
 		log.Debug(ctx, "request", zap.String("operation name", opCtx.OperationName), zap.Int32("user", userId))
 
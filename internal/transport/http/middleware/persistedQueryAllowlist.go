@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"ozon-tesk-task/internal/persisted"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const (
+	errPersistedQueryNotFound   = "PERSISTED_QUERY_NOT_FOUND"
+	errPersistedQueryNotAllowed = "PERSISTED_QUERY_NOT_ALLOWED"
+)
+
+// PersistedQueryAllowlist only executes the operations of the manifest. Clients send the hash of an operation
+// in the persistedQuery extension like for automatic persisted queries, or the full text of an allowed operation.
+type PersistedQueryAllowlist struct {
+	// Queries are the bodies of the allowed operations by their SHA-256 hash
+	Queries map[string]string
+}
+
+var _ interface {
+	graphql.OperationParameterMutator
+	graphql.HandlerExtension
+} = PersistedQueryAllowlist{}
+
+func (a PersistedQueryAllowlist) ExtensionName() string {
+	return "PersistedQueryAllowlist"
+}
+
+func (a PersistedQueryAllowlist) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (a PersistedQueryAllowlist) MutateOperationParameters(ctx context.Context, rawParams *graphql.RawParams) *gqlerror.Error {
+	if rawParams.Query != "" {
+		if _, ok := a.Queries[persisted.Hash(rawParams.Query)]; !ok {
+			err := gqlerror.Errorf("operation is not in the persisted query allowlist")
+			errcode.Set(err, errPersistedQueryNotAllowed)
+			return err
+		}
+
+		return nil
+	}
+
+	extension, _ := rawParams.Extensions["persistedQuery"].(map[string]interface{})
+	hash, _ := extension["sha256Hash"].(string)
+
+	query, ok := a.Queries[hash]
+	if !ok {
+		err := gqlerror.Errorf("PersistedQueryNotFound")
+		errcode.Set(err, errPersistedQueryNotFound)
+		return err
+	}
+
+	rawParams.Query = query
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"ozon-tesk-task/internal/persisted"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+func TestPersistedQueryAllowlist_MutateOperationParameters(t *testing.T) {
+	const (
+		allowed = `query Post { post(id: 1) { id } }`
+		other   = `query Posts { posts { id } }`
+	)
+
+	allowlist := PersistedQueryAllowlist{Queries: map[string]string{persisted.Hash(allowed): allowed}}
+
+	persistedQuery := func(hash string) map[string]interface{} {
+		return map[string]interface{}{
+			"persistedQuery": map[string]interface{}{"version": 1.0, "sha256Hash": hash},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		params    graphql.RawParams
+		wantQuery string
+		wantCode  string
+	}{
+		{
+			name:      "Hash of an operation in the manifest",
+			params:    graphql.RawParams{Extensions: persistedQuery(persisted.Hash(allowed))},
+			wantQuery: allowed,
+		},
+		{
+			name:      "Query in the manifest",
+			params:    graphql.RawParams{Query: allowed},
+			wantQuery: allowed,
+		},
+		{
+			name:      "Query in the manifest with its hash",
+			params:    graphql.RawParams{Query: allowed, Extensions: persistedQuery(persisted.Hash(allowed))},
+			wantQuery: allowed,
+		},
+		{
+			name:     "Query not in the manifest",
+			params:   graphql.RawParams{Query: other},
+			wantCode: errPersistedQueryNotAllowed,
+		},
+		{
+			name:     "Query not in the manifest with the hash of an allowed one",
+			params:   graphql.RawParams{Query: other, Extensions: persistedQuery(persisted.Hash(allowed))},
+			wantCode: errPersistedQueryNotAllowed,
+		},
+		{
+			name:     "Unknown hash",
+			params:   graphql.RawParams{Extensions: persistedQuery(persisted.Hash(other))},
+			wantCode: errPersistedQueryNotFound,
+		},
+		{
+			name:     "Neither query nor hash",
+			params:   graphql.RawParams{},
+			wantCode: errPersistedQueryNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := tt.params
+
+			err := allowlist.MutateOperationParameters(context.Background(), &params)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("PersistedQueryAllowlist.MutateOperationParameters() error = %v", err)
+				}
+				if params.Query != tt.wantQuery {
+					t.Errorf("PersistedQueryAllowlist.MutateOperationParameters() query = %q, want %q", params.Query, tt.wantQuery)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("PersistedQueryAllowlist.MutateOperationParameters() error = nil, want %s", tt.wantCode)
+			}
+			if code := err.Extensions["code"]; code != tt.wantCode {
+				t.Errorf("PersistedQueryAllowlist.MutateOperationParameters() code = %v, want %s", code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"ozon-tesk-task/internal/auth"
+	"ozon-tesk-task/internal/repository"
+	"strings"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/labstack/echo"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const (
+	errSubscriptionLimit = "SUBSCRIPTION_LIMIT_EXCEEDED"
+
+	acceptHeader     = "Accept"
+	eventStreamMedia = "text/event-stream"
+)
+
+var ErrUnauthorized = errors.New("authorization is required")
+
+// WebsocketInit authenticates the connection by the bearer token in the Authorization field of the
+// connection_init payload and stores the user id in the connection context. The token may also be sent
+// in the Authorization header of the upgrade request.
+func WebsocketInit(verifier *auth.Verifier, requireAuth bool) transport.WebsocketInitFunc {
+	return func(ctx context.Context, initPayload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		token := initPayload.Authorization()
+		if token == "" {
+			if _, ok := auth.User(ctx); requireAuth && !ok {
+				return nil, nil, ErrUnauthorized
+			}
+
+			return ctx, nil, nil
+		}
+
+		userId, err := verifier.Verify(token)
+		if err != nil {
+			return nil, nil, repository.ErrUnauthenticated
+		}
+
+		ctx = context.WithValue(ctx, UserIDKey, userId)
+
+		return auth.WithUser(ctx, userId), nil, nil
+	}
+}
+
+// ConnectionLimitMiddleware limits the long-lived connections, WebSocket and SSE, of a client address.
+func ConnectionLimitMiddleware(max int, proxies TrustedProxies) echo.MiddlewareFunc {
+	var (
+		connections = make(map[string]int)
+		lock        sync.Mutex
+	)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			longLived := req.Header.Get(echo.HeaderUpgrade) != "" || strings.Contains(req.Header.Get(acceptHeader), eventStreamMedia)
+			if max <= 0 || !longLived {
+				return next(c)
+			}
+
+			ip := proxies.ClientIP(req)
+
+			lock.Lock()
+			if connections[ip] >= max {
+				lock.Unlock()
+				return c.String(http.StatusTooManyRequests, "too many connections")
+			}
+			connections[ip]++
+			lock.Unlock()
+
+			defer func() {
+				lock.Lock()
+				defer lock.Unlock()
+
+				connections[ip]--
+				if connections[ip] == 0 {
+					delete(connections, ip)
+				}
+			}()
+
+			return next(c)
+		}
+	}
+}
+
+// SubscriptionLimitMiddleware limits the active subscriptions of a client. Authenticated clients are limited per user,
+// anonymous ones per connection, as the user agents and addresses they are told apart by are shared.
+func SubscriptionLimitMiddleware(max int) graphql.OperationMiddleware {
+	var (
+		subscriptions = make(map[string]int)
+		lock          sync.Mutex
+	)
+
+	return func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		opCtx := graphql.GetOperationContext(ctx)
+		if max <= 0 || opCtx.Operation == nil || opCtx.Operation.Operation != ast.Subscription {
+			return next(ctx)
+		}
+
+		client := auth.Scope(ctx)
+
+		lock.Lock()
+		if subscriptions[client] >= max {
+			lock.Unlock()
+			return graphql.OneShot(&graphql.Response{
+				Errors: gqlerror.List{{
+					Message: "too many subscriptions",
+					Extensions: map[string]interface{}{
+						"code": errSubscriptionLimit,
+					},
+				}},
+			})
+		}
+		subscriptions[client]++
+		lock.Unlock()
+
+		var once sync.Once
+		release := func() {
+			lock.Lock()
+			defer lock.Unlock()
+
+			subscriptions[client]--
+			if subscriptions[client] == 0 {
+				delete(subscriptions, client)
+			}
+		}
+
+		responses := next(ctx)
+
+		return func(ctx context.Context) *graphql.Response {
+			response := responses(ctx)
+			if response == nil {
+				once.Do(release)
+			}
+
+			return response
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"ozon-tesk-task/internal/auth"
+	"ozon-tesk-task/internal/auth/authtest"
+	"ozon-tesk-task/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/labstack/echo"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const testSecret = "secret"
+
+func TestConnectionLimitMiddleware(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		proxies    TrustedProxies
+		first      *http.Request
+		second     *http.Request
+		wantStatus int
+	}{
+		{
+			name:       "Second connection of a client",
+			first:      connection("1.1.1.1:1000", nil),
+			second:     connection("1.1.1.1:1001", nil),
+			wantStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:       "Connections of different clients",
+			first:      connection("1.1.1.1:1000", nil),
+			second:     connection("2.2.2.2:1000", nil),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:  "Spoofed forwarding headers are ignored",
+			first: connection("1.1.1.1:1000", nil),
+			second: connection("1.1.1.1:1001", map[string]string{
+				echo.HeaderXForwardedFor: "3.3.3.3",
+				echo.HeaderXRealIP:       "4.4.4.4",
+			}),
+			wantStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:       "Trusted proxy forwards different clients",
+			proxies:    proxies,
+			first:      connection("10.0.0.1:1000", map[string]string{echo.HeaderXForwardedFor: "1.1.1.1"}),
+			second:     connection("10.0.0.1:1001", map[string]string{echo.HeaderXForwardedFor: "2.2.2.2"}),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Trusted proxies forward the same client",
+			proxies:    proxies,
+			first:      connection("10.0.0.1:1000", map[string]string{echo.HeaderXForwardedFor: "1.1.1.1"}),
+			second:     connection("192.168.1.1:1000", map[string]string{echo.HeaderXForwardedFor: "5.5.5.5, 1.1.1.1, 10.0.0.1"}),
+			wantStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:       "SSE connections are counted",
+			first:      stream("1.1.1.1:1000"),
+			second:     stream("1.1.1.1:1001"),
+			wantStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:       "Short-lived requests are not counted",
+			first:      connection("1.1.1.1:1000", nil),
+			second:     httptest.NewRequest(http.MethodPost, "/query", nil),
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			limit := ConnectionLimitMiddleware(1, tt.proxies)
+
+			// the second request is served while the first connection is still open
+			var (
+				status int
+				second = tt.second
+			)
+			var handler echo.HandlerFunc
+			handler = limit(func(c echo.Context) error {
+				if second != nil {
+					req := second
+					second = nil
+
+					rec := httptest.NewRecorder()
+					if err := handler(e.NewContext(req, rec)); err != nil {
+						t.Fatal(err)
+					}
+					status = rec.Code
+				}
+
+				return c.NoContent(http.StatusOK)
+			})
+
+			rec := httptest.NewRecorder()
+			if err := handler(e.NewContext(tt.first, rec)); err != nil {
+				t.Fatal(err)
+			}
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("first connection status = %d", rec.Code)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("second connection status = %d, want %d", status, tt.wantStatus)
+			}
+
+			// the closed connections are not counted anymore
+			rec = httptest.NewRecorder()
+			if err := limit(func(c echo.Context) error { return c.NoContent(http.StatusOK) })(e.NewContext(tt.first, rec)); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusOK {
+				t.Errorf("connection after close status = %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	if _, err := ParseTrustedProxies("10.0.0.1, ::1, 172.16.0.0/12"); err != nil {
+		t.Errorf("ParseTrustedProxies() error = %v", err)
+	}
+	if _, err := ParseTrustedProxies("proxy.local"); err == nil {
+		t.Error("ParseTrustedProxies() accepted a host name")
+	}
+}
+
+func TestSubscriptionLimitMiddleware(t *testing.T) {
+	limit := SubscriptionLimitMiddleware(1)
+
+	subscribe := func(ctx context.Context) (graphql.ResponseHandler, func()) {
+		ctx = graphql.WithOperationContext(ctx, &graphql.OperationContext{
+			Operation: &ast.OperationDefinition{Operation: ast.Subscription},
+		})
+
+		done := false
+		responses := limit(ctx, func(ctx context.Context) graphql.ResponseHandler {
+			return func(ctx context.Context) *graphql.Response {
+				if done {
+					return nil
+				}
+				return &graphql.Response{}
+			}
+		})
+
+		return responses, func() { done = true }
+	}
+
+	rejected := func(responses graphql.ResponseHandler) bool {
+		response := responses(context.Background())
+		return response != nil && len(response.Errors) > 0
+	}
+
+	user := auth.WithUser(auth.WithConnection(context.Background(), "1.1.1.1:1000"), 42)
+	sameUser := auth.WithUser(auth.WithConnection(context.Background(), "2.2.2.2:1000"), 42)
+	connection := auth.WithConnection(context.Background(), "1.1.1.1:1000")
+	otherConnection := auth.WithConnection(context.Background(), "1.1.1.1:1001")
+
+	first, unsubscribe := subscribe(user)
+	if rejected(first) {
+		t.Fatal("first subscription of the user was rejected")
+	}
+
+	if second, _ := subscribe(sameUser); !rejected(second) {
+		t.Error("subscription of the same user over another connection was accepted")
+	}
+
+	anonymous, _ := subscribe(connection)
+	if rejected(anonymous) {
+		t.Error("anonymous subscription was limited by the user of the connection address")
+	}
+	if other, _ := subscribe(otherConnection); rejected(other) {
+		t.Error("anonymous subscriptions of different connections share the limit")
+	}
+
+	unsubscribe()
+	if first(context.Background()) != nil {
+		t.Fatal("subscription did not complete")
+	}
+	if again, _ := subscribe(sameUser); rejected(again) {
+		t.Error("completed subscription was still counted")
+	}
+}
+
+func TestWebsocketInit(t *testing.T) {
+	verifier := auth.NewVerifier(testSecret)
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		requireAuth bool
+		token       string
+		wantUser    int32
+		wantErr     error
+	}{
+		{
+			name:     "Valid token",
+			ctx:      context.Background(),
+			token:    authtest.Sign(testSecret, 42, time.Hour),
+			wantUser: 42,
+		},
+		{
+			name:    "Invalid token",
+			ctx:     context.Background(),
+			token:   "any-token",
+			wantErr: repository.ErrUnauthenticated,
+		},
+		{
+			name:    "Expired token",
+			ctx:     context.Background(),
+			token:   authtest.Sign(testSecret, 42, -time.Minute),
+			wantErr: repository.ErrUnauthenticated,
+		},
+		{
+			name: "Anonymous connection",
+			ctx:  context.Background(),
+		},
+		{
+			name:        "Anonymous connection when authentication is required",
+			ctx:         context.Background(),
+			requireAuth: true,
+			wantErr:     ErrUnauthorized,
+		},
+		{
+			name:        "Connection authenticated by the upgrade request",
+			ctx:         auth.WithUser(context.Background(), 7),
+			requireAuth: true,
+			wantUser:    7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := transport.InitPayload{}
+			if tt.token != "" {
+				payload["Authorization"] = tt.token
+			}
+
+			ctx, _, err := WebsocketInit(verifier, tt.requireAuth)(tt.ctx, payload)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WebsocketInit() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			userId, ok := auth.User(ctx)
+			if ok != (tt.wantUser != 0) || userId != tt.wantUser {
+				t.Errorf("WebsocketInit() user = %d, want %d", userId, tt.wantUser)
+			}
+		})
+	}
+}
+
+func connection(remoteAddr string, headers map[string]string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/query", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set(echo.HeaderUpgrade, "websocket")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	return req
+}
+
+func stream(remoteAddr string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/query", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set(acceptHeader, eventStreamMedia)
+
+	return req
+}
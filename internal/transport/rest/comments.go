@@ -0,0 +1,138 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"ozon-tesk-task/internal/transport/http/middleware"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+type CreateCommentRequest struct {
+	// Global or integer id of the comment to reply to
+	ParentID *string `json:"parentId,omitempty"`
+	Content  *string `json:"content"`
+}
+
+func (h *Handler) listComments(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	p := &params{c: c}
+	postId := p.id("id", model.NodeTypePost)
+	page, limit := p.page()
+	maxDepth := p.int32("maxDepth", repository.UnlimitedDepth, 0)
+	if p.errors != nil {
+		return writeValidationError(c, p.errors)
+	}
+
+	h.logs.Debug(ctx, "Loading comments", zap.Int32("post", postId), zap.Int32("page", page))
+
+	comments, err := h.service.GetComments(ctx, postId, maxDepth, limit, limit*(page-1))
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		h.logs.Error(ctx, "failed to list comments", zap.String("err", err.Error()))
+		return writeError(c, err)
+	}
+	if comments == nil {
+		comments = []*model.Comment{}
+	}
+
+	setPagination(c, page, limit, len(comments))
+
+	return c.JSON(http.StatusOK, comments)
+}
+
+func (h *Handler) createComment(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	p := &params{c: c}
+	postId := p.id("id", model.NodeTypePost)
+
+	var (
+		req      CreateCommentRequest
+		parentId *int32
+	)
+	if p.body(&req) {
+		h.checkString(p, "CreateCommentInput", "content", req.Content)
+		if req.ParentID != nil {
+			id, ok := model.ParseID(model.NodeTypeComment, *req.ParentID)
+			if !ok {
+				p.invalid("parentId", fmt.Sprintf("must be a %s id", model.NodeTypeComment))
+			}
+
+			parentId = &id
+		}
+	}
+	if p.errors != nil {
+		return writeValidationError(c, p.errors)
+	}
+
+	h.logs.Debug(ctx, "Creating comment", zap.Int32("post", postId), zap.Any("input", req))
+
+	author, _ := ctx.Value(middleware.UserIDKey).(int32)
+
+	comment, replayed, err := h.service.CreateComment(ctx, &model.Comment{
+		PostID:   postId,
+		ParentID: parentId,
+		Content:  *req.Content,
+		Author:   author,
+	}, c.Request().Header.Get(idempotencyKeyHeader))
+	if err != nil {
+		h.logs.Error(ctx, "failed to create comment", zap.String("err", err.Error()))
+		return writeError(c, err)
+	}
+
+	if replayed {
+		h.logs.Debug(ctx, "Replayed comment for idempotency key", zap.Int32("id", comment.ID))
+		c.Response().Header().Set(replayedHeader, "true")
+	} else {
+		h.ps.Publish(ctx, comment)
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("%s/comments/%d", basePath, comment.ID))
+
+	return c.JSON(http.StatusCreated, comment)
+}
+
+func (h *Handler) getComment(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	p := &params{c: c}
+	commentId := p.id("id", model.NodeTypeComment)
+	maxDepth := p.int32("maxDepth", repository.UnlimitedDepth, 0)
+	if p.errors != nil {
+		return writeValidationError(c, p.errors)
+	}
+
+	h.logs.Debug(ctx, "Loading comment thread", zap.Int32("id", commentId), zap.Int32("max depth", maxDepth))
+
+	thread, err := h.service.GetCommentThread(ctx, commentId, maxDepth)
+	if err != nil {
+		h.logs.Error(ctx, "failed to get comment thread", zap.String("err", err.Error()))
+		return writeError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, thread)
+}
+
+func (h *Handler) deleteComment(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	p := &params{c: c}
+	commentId := p.id("id", model.NodeTypeComment)
+	if p.errors != nil {
+		return writeValidationError(c, p.errors)
+	}
+
+	h.logs.Debug(ctx, "Deleting comment", zap.Int32("id", commentId))
+
+	if err := h.service.DeleteComment(ctx, commentId); err != nil {
+		h.logs.Error(ctx, "failed to delete comment", zap.String("err", err.Error()))
+		return writeError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"ozon-tesk-task/internal/repository"
+
+	"github.com/labstack/echo"
+)
+
+const codeInternal = "INTERNAL"
+
+// ErrorBody is the body of every failed response.
+type ErrorBody struct {
+	Error ErrorDetails `json:"error"`
+}
+
+type ErrorDetails struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError describes an invalid field of the request body or an invalid parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+var statuses = map[repository.Code]int{
+	repository.CodeNotFound:        http.StatusNotFound,
+	repository.CodeValidation:      http.StatusBadRequest,
+	repository.CodeForbidden:       http.StatusForbidden,
+	repository.CodeConflict:        http.StatusConflict,
+	repository.CodeCommentsLocked:  http.StatusForbidden,
+	repository.CodeCommentTooDeep:  http.StatusUnprocessableEntity,
+	repository.CodeUnauthenticated: http.StatusUnauthorized,
+}
+
+// writeError shows domain errors with their code and hides any other error behind INTERNAL,
+// the same way the GraphQL error presenter does.
+func writeError(c echo.Context, err error) error {
+	var domainErr *repository.Error
+	if errors.As(err, &domainErr) {
+		status, ok := statuses[domainErr.Code]
+		if !ok {
+			status = http.StatusBadRequest
+		}
+
+		return c.JSON(status, ErrorBody{Error: ErrorDetails{Code: string(domainErr.Code), Message: domainErr.Message}})
+	}
+
+	return c.JSON(http.StatusInternalServerError, ErrorBody{Error: ErrorDetails{Code: codeInternal, Message: "internal server error"}})
+}
+
+func writeValidationError(c echo.Context, fields []FieldError) error {
+	return c.JSON(http.StatusBadRequest, ErrorBody{Error: ErrorDetails{
+		Code:    string(repository.CodeValidation),
+		Message: "request is invalid",
+		Fields:  fields,
+	}})
+}
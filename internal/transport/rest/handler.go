@@ -0,0 +1,154 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"ozon-tesk-task/internal/auth"
+	"ozon-tesk-task/internal/config"
+	"ozon-tesk-task/internal/transport/graph"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"ozon-tesk-task/internal/transport/http/middleware"
+	"ozon-tesk-task/pkg/logger"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	basePath = "/api/v1"
+
+	idempotencyKeyHeader = "Idempotency-Key"
+	replayedHeader       = "Idempotent-Replayed"
+)
+
+type Handler struct {
+	cfg       *config.Config
+	service   graph.Service
+	logs      logger.Logger
+	ps        graph.PubSub
+	validator *graph.InputValidator
+}
+
+// route is a REST endpoint together with what the OpenAPI document says about it.
+type route struct {
+	method   string
+	path     string
+	name     string
+	summary  string
+	params   []param
+	body     interface{}
+	status   int
+	response interface{}
+	// paginated routes set the pagination headers
+	paginated bool
+	handler   echo.HandlerFunc
+}
+
+type param struct {
+	name        string
+	in          string
+	typ         string
+	description string
+}
+
+// NewHandler registers the REST endpoints under /api/v1 along with their OpenAPI document at /api/v1/openapi.json.
+func NewHandler(e *echo.Echo, cfg *config.Config, service graph.Service, ps graph.PubSub, logs logger.Logger) error {
+	validator, err := graph.NewInputValidator(graph.NewExecutableSchema(graph.Config{}).Schema(), cfg)
+	if err != nil {
+		return err
+	}
+
+	handler := &Handler{
+		cfg:       cfg,
+		service:   service,
+		logs:      logs,
+		ps:        ps,
+		validator: validator,
+	}
+
+	proxies, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	routes := handler.routes()
+
+	document, err := json.Marshal(openAPIDocument(routes))
+	if err != nil {
+		return err
+	}
+
+	g := e.Group(basePath, middleware.IdentityMiddleware(auth.NewVerifier(cfg.TokenSecret), proxies, writeError))
+	for _, r := range routes {
+		g.Add(r.method, r.path, r.handler)
+	}
+	g.GET("/openapi.json", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, document)
+	})
+
+	return nil
+}
+
+func (h *Handler) routes() []route {
+	var (
+		postID    = param{name: "id", in: "path", typ: "string", description: "global or integer id of the post"}
+		commentID = param{name: "id", in: "path", typ: "string", description: "global or integer id of the comment"}
+		page      = param{name: "page", in: "query", typ: "integer", description: "page number, starts at 1"}
+		limit     = param{name: "limit", in: "query", typ: "integer", description: "page size, 10 by default"}
+		comments  = param{name: "comments", in: "query", typ: "boolean", description: "include the comment trees"}
+		maxDepth  = param{name: "maxDepth", in: "query", typ: "integer", description: "maximum depth of the returned replies"}
+		key       = param{name: idempotencyKeyHeader, in: "header", typ: "string", description: "makes retries safe"}
+	)
+
+	return []route{
+		{
+			method: http.MethodGet, path: "/posts", name: "listPosts", summary: "List posts",
+			params: []param{page, limit, comments},
+			status: http.StatusOK, response: []*model.Post{}, paginated: true,
+			handler: h.listPosts,
+		},
+		{
+			method: http.MethodPost, path: "/posts", name: "createPost", summary: "Create a post",
+			params: []param{key},
+			body:   CreatePostRequest{},
+			status: http.StatusCreated, response: model.Post{},
+			handler: h.createPost,
+		},
+		{
+			method: http.MethodGet, path: "/posts/:id", name: "getPost", summary: "Get a post",
+			params: []param{postID, comments},
+			status: http.StatusOK, response: model.Post{},
+			handler: h.getPost,
+		},
+		{
+			method: http.MethodDelete, path: "/posts/:id", name: "deletePost", summary: "Delete a post with its comments",
+			params:  []param{postID},
+			status:  http.StatusNoContent,
+			handler: h.deletePost,
+		},
+		{
+			method: http.MethodGet, path: "/posts/:id/comments", name: "listComments", summary: "List comment trees of a post",
+			params: []param{postID, page, limit, maxDepth},
+			status: http.StatusOK, response: []*model.Comment{}, paginated: true,
+			handler: h.listComments,
+		},
+		{
+			method: http.MethodPost, path: "/posts/:id/comments", name: "createComment", summary: "Comment on a post or reply to a comment",
+			params: []param{postID, key},
+			body:   CreateCommentRequest{},
+			status: http.StatusCreated, response: model.Comment{},
+			handler: h.createComment,
+		},
+		{
+			method: http.MethodGet, path: "/comments/:id", name: "getComment", summary: "Get a comment with its replies",
+			params: []param{commentID, maxDepth},
+			status: http.StatusOK, response: model.Comment{},
+			handler: h.getComment,
+		},
+		{
+			method: http.MethodDelete, path: "/comments/:id", name: "deleteComment", summary: "Delete a comment without replies",
+			params:  []param{commentID},
+			status:  http.StatusNoContent,
+			handler: h.deleteComment,
+		},
+	}
+}
@@ -0,0 +1,284 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"ozon-tesk-task/internal/config"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/internal/transport/graph/mocks"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"ozon-tesk-task/pkg/logger"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler(t *testing.T) {
+	post := &model.Post{ID: 1, Title: "title", Content: "content", AllowComments: true}
+	comment := &model.Comment{ID: 2, PostID: 1, Content: "comment"}
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		body        string
+		headers     map[string]string
+		serviceMock func(s *mocks.Service, p *mocks.PubSub)
+		wantStatus  int
+		wantCode    string
+		wantFields  []string
+		wantHeaders map[string]string
+	}{
+		{
+			name:   "List posts",
+			method: http.MethodGet,
+			target: "/api/v1/posts?page=2&limit=1",
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {
+				s.On("ListPosts", mock.Anything, int32(1), int32(1), false).Return([]*model.Post{post}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				headerPage:    "2",
+				headerPerPage: "1",
+				headerLink:    `</api/v1/posts?limit=1&page=3>; rel="next", </api/v1/posts?limit=1&page=1>; rel="prev"`,
+			},
+		},
+		{
+			name:   "Empty page of posts",
+			method: http.MethodGet,
+			target: "/api/v1/posts",
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {
+				s.On("ListPosts", mock.Anything, int32(10), int32(0), false).Return(nil, repository.ErrNotFound)
+			},
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{headerLink: ""},
+		},
+		{
+			name:        "Invalid pagination",
+			method:      http.MethodGet,
+			target:      "/api/v1/posts?page=0&limit=x",
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {},
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    string(repository.CodeValidation),
+			wantFields:  []string{"page", "limit"},
+		},
+		{
+			name:   "Create post",
+			method: http.MethodPost,
+			target: "/api/v1/posts",
+			body:   `{"title": "title", "content": "content", "allowComments": true}`,
+			headers: map[string]string{
+				idempotencyKeyHeader: "key",
+			},
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {
+				s.On("CreatePost", mock.Anything, mock.MatchedBy(func(p *model.Post) bool {
+					return p.Title == "title" && p.AllowComments
+				}), "key").Return(post, nil)
+			},
+			wantStatus:  http.StatusCreated,
+			wantHeaders: map[string]string{echo.HeaderLocation: "/api/v1/posts/1"},
+		},
+		{
+			name:        "Create post with invalid fields",
+			method:      http.MethodPost,
+			target:      "/api/v1/posts",
+			body:        `{"title": "", "content": "content"}`,
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {},
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    string(repository.CodeValidation),
+			wantFields:  []string{"title", "allowComments"},
+		},
+		{
+			name:        "Create post with unknown field",
+			method:      http.MethodPost,
+			target:      "/api/v1/posts",
+			body:        `{"titel": "title"}`,
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {},
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    string(repository.CodeValidation),
+			wantFields:  []string{"body"},
+		},
+		{
+			name:   "Get post by global id",
+			method: http.MethodGet,
+			target: "/api/v1/posts/" + post.GetID() + "?comments=true",
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {
+				s.On("GetPostById", mock.Anything, int32(1), true).Return(post, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "Missing post",
+			method: http.MethodGet,
+			target: "/api/v1/posts/5",
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {
+				s.On("GetPostById", mock.Anything, int32(5), false).Return(nil, repository.ErrWrongPostId)
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   string(repository.CodeNotFound),
+		},
+		{
+			name:        "Comment id for a post",
+			method:      http.MethodDelete,
+			target:      "/api/v1/posts/" + comment.GetID(),
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {},
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    string(repository.CodeValidation),
+			wantFields:  []string{"id"},
+		},
+		{
+			name:   "Delete post",
+			method: http.MethodDelete,
+			target: "/api/v1/posts/1",
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {
+				s.On("DeletePost", mock.Anything, int32(1)).Return(nil)
+				p.On("Evict", mock.Anything, int32(1)).Return()
+			},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:   "List comments",
+			method: http.MethodGet,
+			target: "/api/v1/posts/1/comments?maxDepth=1",
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {
+				s.On("GetComments", mock.Anything, int32(1), int32(1), int32(10), int32(0)).Return(nil, nil)
+			},
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{headerLink: ""},
+		},
+		{
+			name:   "Comments are locked",
+			method: http.MethodGet,
+			target: "/api/v1/posts/1/comments",
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {
+				s.On("GetComments", mock.Anything, int32(1), repository.UnlimitedDepth, int32(10), int32(0)).Return(nil, repository.ErrCommentsNotAllowed)
+			},
+			wantStatus: http.StatusForbidden,
+			wantCode:   string(repository.CodeCommentsLocked),
+		},
+		{
+			name:   "Create comment",
+			method: http.MethodPost,
+			target: "/api/v1/posts/1/comments",
+			body:   `{"content": "comment", "parentId": "` + comment.GetID() + `"}`,
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {
+				s.On("CreateComment", mock.Anything, mock.MatchedBy(func(c *model.Comment) bool {
+					return c.PostID == 1 && c.ParentID != nil && *c.ParentID == 2
+				}), "").Return(comment, false, nil)
+				p.On("Publish", mock.Anything, comment).Return()
+			},
+			wantStatus:  http.StatusCreated,
+			wantHeaders: map[string]string{echo.HeaderLocation: "/api/v1/comments/2", replayedHeader: ""},
+		},
+		{
+			name:   "Replayed comment is not published again",
+			method: http.MethodPost,
+			target: "/api/v1/posts/1/comments",
+			body:   `{"content": "comment"}`,
+			headers: map[string]string{
+				idempotencyKeyHeader: "key",
+			},
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {
+				s.On("CreateComment", mock.Anything, mock.Anything, "key").Return(comment, true, nil)
+			},
+			wantStatus:  http.StatusCreated,
+			wantHeaders: map[string]string{replayedHeader: "true"},
+		},
+		{
+			name:        "Create comment with too long content",
+			method:      http.MethodPost,
+			target:      "/api/v1/posts/1/comments",
+			body:        `{"content": "` + strings.Repeat("a", 2001) + `"}`,
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {},
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    string(repository.CodeValidation),
+			wantFields:  []string{"content"},
+		},
+		{
+			name:   "Get comment thread",
+			method: http.MethodGet,
+			target: "/api/v1/comments/2",
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {
+				s.On("GetCommentThread", mock.Anything, int32(2), repository.UnlimitedDepth).Return(comment, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "Delete comment with replies",
+			method: http.MethodDelete,
+			target: "/api/v1/comments/2",
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {
+				s.On("DeleteComment", mock.Anything, int32(2)).Return(repository.ErrCommentHasReplies)
+			},
+			wantStatus: http.StatusConflict,
+			wantCode:   string(repository.CodeConflict),
+		},
+		{
+			name:   "Internal error",
+			method: http.MethodDelete,
+			target: "/api/v1/comments/2",
+			serviceMock: func(s *mocks.Service, p *mocks.PubSub) {
+				s.On("DeleteComment", mock.Anything, int32(2)).Return(errors.New("connection refused"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   codeInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mocks.NewService(t)
+			p := mocks.NewPubSub(t)
+			log, _ := logger.New("test")
+
+			tt.serviceMock(s, p)
+
+			e := echo.New()
+			if err := NewHandler(e, &config.Config{}, s, p, log); err != nil {
+				t.Fatalf("NewHandler() error = %v", err)
+			}
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			for name, want := range tt.wantHeaders {
+				if got := rec.Header().Get(name); got != want {
+					t.Errorf("header %s = %q, want %q", name, got, want)
+				}
+			}
+
+			if tt.wantCode == "" {
+				return
+			}
+
+			var body ErrorBody
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode error body: %v", err)
+			}
+
+			if body.Error.Code != tt.wantCode {
+				t.Errorf("error code = %s, want %s", body.Error.Code, tt.wantCode)
+			}
+
+			var fields []string
+			for _, field := range body.Error.Fields {
+				fields = append(fields, field.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("invalid fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package rest
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+const openAPIVersion = "3.0.3"
+
+// openAPIDocument describes the routes, the schemas of the bodies are derived from their Go types.
+func openAPIDocument(routes []route) map[string]interface{} {
+	components := make(map[string]interface{})
+	paths := make(map[string]map[string]interface{})
+
+	errorResponse := map[string]interface{}{
+		"description": "error",
+		"content":     jsonContent(schemaOf(reflect.TypeOf(ErrorBody{}), components)),
+	}
+
+	for _, r := range routes {
+		path := basePath + openAPIPath(r.path)
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+
+		operation := map[string]interface{}{
+			"operationId": r.name,
+			"summary":     r.summary,
+		}
+
+		var parameters []interface{}
+		for _, p := range r.params {
+			parameters = append(parameters, map[string]interface{}{
+				"name":        p.name,
+				"in":          p.in,
+				"description": p.description,
+				"required":    p.in == "path",
+				"schema":      map[string]interface{}{"type": p.typ},
+			})
+		}
+		if parameters != nil {
+			operation["parameters"] = parameters
+		}
+
+		if r.body != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(schemaOf(reflect.TypeOf(r.body), components)),
+			}
+		}
+
+		success := map[string]interface{}{
+			"description": http.StatusText(r.status),
+		}
+		if r.response != nil {
+			success["content"] = jsonContent(schemaOf(reflect.TypeOf(r.response), components))
+		}
+		if r.paginated {
+			success["headers"] = paginationHeaders()
+		}
+
+		operation["responses"] = map[string]interface{}{
+			strconv.Itoa(r.status): success,
+			"default":              errorResponse,
+		}
+
+		paths[path][strings.ToLower(r.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":   "Posts and comments",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": components,
+		},
+	}
+}
+
+// openAPIPath turns echo path parameters, e.g. /posts/:id, into OpenAPI ones, e.g. /posts/{id}.
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		echo.MIMEApplicationJSON: map[string]interface{}{"schema": schema},
+	}
+}
+
+func paginationHeaders() map[string]interface{} {
+	header := func(description, typ string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"schema":      map[string]interface{}{"type": typ},
+		}
+	}
+
+	return map[string]interface{}{
+		headerPage:    header("current page", "integer"),
+		headerPerPage: header("maximum number of items of the page", "integer"),
+		headerLink:    header("links to the next and previous pages, RFC 8288", "string"),
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf returns the schema of the JSON encoding of the type. Named structs are put into the components
+// and referenced, so recursive types, e.g. comment replies, terminate. A field is required unless it is omitempty.
+func schemaOf(t reflect.Type, components map[string]interface{}) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct:
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := components[t.Name()]; ok {
+			return ref
+		}
+
+		// Reserve the name before walking the fields to stop on recursion.
+		components[t.Name()] = nil
+
+		properties := make(map[string]interface{})
+		var required []string
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+
+			name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			properties[name] = schemaOf(field.Type, components)
+			if !strings.Contains(options, "omitempty") {
+				required = append(required, name)
+			}
+		}
+
+		schema := map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+		}
+		if required != nil {
+			schema["required"] = required
+		}
+
+		components[t.Name()] = schema
+
+		return ref
+	case t.Kind() == reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), components)}
+	case t.Kind() == reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case t.Kind() == reflect.Int32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	}
+
+	return map[string]interface{}{}
+}
@@ -0,0 +1,39 @@
+package rest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	h := &Handler{}
+	routes := h.routes()
+
+	document := openAPIDocument(routes)
+
+	paths := document["paths"].(map[string]map[string]interface{})
+
+	operations := 0
+	for _, path := range paths {
+		operations += len(path)
+	}
+	if operations != len(routes) {
+		t.Errorf("document has %d operations, want %d", operations, len(routes))
+	}
+
+	if _, ok := paths["/api/v1/posts/{id}/comments"]["post"]; !ok {
+		t.Error("document misses createComment")
+	}
+
+	schemas := document["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	for _, name := range []string{"Post", "Comment", "CreatePostRequest", "CreateCommentRequest", "ErrorBody"} {
+		if schemas[name] == nil {
+			t.Errorf("document misses the %s schema", name)
+		}
+	}
+
+	request := schemas["CreateCommentRequest"].(map[string]interface{})
+	if got := request["required"]; !reflect.DeepEqual(got, []string{"content"}) {
+		t.Errorf("CreateCommentRequest required = %v, want [content]", got)
+	}
+}
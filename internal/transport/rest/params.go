@@ -0,0 +1,116 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	defaultLimit = 10
+
+	headerPage    = "X-Page"
+	headerPerPage = "X-Per-Page"
+	headerLink    = "Link"
+)
+
+// params reads path and query parameters and collects every invalid one, so they are reported at once.
+type params struct {
+	c      echo.Context
+	errors []FieldError
+}
+
+func (p *params) invalid(field, message string) {
+	p.errors = append(p.errors, FieldError{Field: field, Message: message})
+}
+
+func (p *params) id(name, nodeType string) int32 {
+	id, ok := model.ParseID(nodeType, p.c.Param(name))
+	if !ok {
+		p.invalid(name, fmt.Sprintf("must be a %s id", nodeType))
+	}
+
+	return id
+}
+
+func (p *params) int32(name string, def, min int32) int32 {
+	raw := p.c.QueryParam(name)
+	if raw == "" {
+		return def
+	}
+
+	value, err := strconv.ParseInt(raw, 10, 32)
+	if err != nil || int32(value) < min {
+		p.invalid(name, fmt.Sprintf("must be an integer of at least %d", min))
+		return def
+	}
+
+	return int32(value)
+}
+
+func (p *params) bool(name string) bool {
+	raw := p.c.QueryParam(name)
+	if raw == "" {
+		return false
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		p.invalid(name, "must be a boolean")
+	}
+
+	return value
+}
+
+// page returns the page number and size, pages start at 1.
+func (p *params) page() (page, limit int32) {
+	return p.int32("page", 1, 1), p.int32("limit", defaultLimit, 1)
+}
+
+// body decodes the JSON request body, unknown fields are rejected to catch typos.
+func (p *params) body(v interface{}) bool {
+	decoder := json.NewDecoder(p.c.Request().Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		p.invalid("body", strings.TrimPrefix(err.Error(), "json: "))
+		return false
+	}
+
+	return true
+}
+
+// setPagination sets the page headers and links to the neighbouring pages. The next page is only linked while pages are full,
+// as the total number of items is not counted.
+func setPagination(c echo.Context, page, limit int32, count int) {
+	header := c.Response().Header()
+	header.Set(headerPage, strconv.Itoa(int(page)))
+	header.Set(headerPerPage, strconv.Itoa(int(limit)))
+
+	link := func(page int32, rel string) string {
+		query := c.Request().URL.Query()
+		query.Set("page", strconv.Itoa(int(page)))
+		query.Set("limit", strconv.Itoa(int(limit)))
+
+		u := url.URL{Path: c.Request().URL.Path, RawQuery: query.Encode()}
+
+		return fmt.Sprintf("<%s>; rel=%q", u.String(), rel)
+	}
+
+	var links []string
+	if count >= int(limit) {
+		links = append(links, link(page+1, "next"))
+	}
+	if page > 1 {
+		links = append(links, link(page-1, "prev"))
+	}
+
+	if len(links) > 0 {
+		header.Set(headerLink, strings.Join(links, ", "))
+	}
+}
@@ -0,0 +1,135 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"ozon-tesk-task/internal/repository"
+	"ozon-tesk-task/internal/transport/graph/model"
+	"ozon-tesk-task/internal/transport/http/middleware"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+type CreatePostRequest struct {
+	Title         *string `json:"title"`
+	Content       *string `json:"content"`
+	AllowComments *bool   `json:"allowComments"`
+}
+
+func (h *Handler) listPosts(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	p := &params{c: c}
+	page, limit := p.page()
+	withComments := p.bool("comments")
+	if p.errors != nil {
+		return writeValidationError(c, p.errors)
+	}
+
+	h.logs.Debug(ctx, "Loading posts", zap.Bool("with comments", withComments), zap.Int32("page", page))
+
+	posts, err := h.service.ListPosts(ctx, limit, limit*(page-1), withComments)
+	if errors.Is(err, repository.ErrNotFound) {
+		posts = []*model.Post{}
+	} else if err != nil {
+		h.logs.Error(ctx, "failed to list posts", zap.String("err", err.Error()))
+		return writeError(c, err)
+	}
+
+	setPagination(c, page, limit, len(posts))
+
+	return c.JSON(http.StatusOK, posts)
+}
+
+func (h *Handler) createPost(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	p := &params{c: c}
+
+	var req CreatePostRequest
+	if p.body(&req) {
+		h.checkString(p, "CreatePostInput", "title", req.Title)
+		h.checkString(p, "CreatePostInput", "content", req.Content)
+		if req.AllowComments == nil {
+			p.invalid("allowComments", "is required")
+		}
+	}
+	if p.errors != nil {
+		return writeValidationError(c, p.errors)
+	}
+
+	h.logs.Debug(ctx, "Creating post", zap.Any("input", req))
+
+	author, _ := ctx.Value(middleware.UserIDKey).(int32)
+
+	post, err := h.service.CreatePost(ctx, &model.Post{
+		Title:         *req.Title,
+		Content:       *req.Content,
+		AllowComments: *req.AllowComments,
+		Author:        author,
+	}, c.Request().Header.Get(idempotencyKeyHeader))
+	if err != nil {
+		h.logs.Error(ctx, "failed to create post", zap.String("err", err.Error()))
+		return writeError(c, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("%s/posts/%d", basePath, post.ID))
+
+	return c.JSON(http.StatusCreated, post)
+}
+
+func (h *Handler) getPost(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	p := &params{c: c}
+	postId := p.id("id", model.NodeTypePost)
+	withComments := p.bool("comments")
+	if p.errors != nil {
+		return writeValidationError(c, p.errors)
+	}
+
+	h.logs.Debug(ctx, "Loading post", zap.Int32("id", postId), zap.Bool("with comments", withComments))
+
+	post, err := h.service.GetPostById(ctx, postId, withComments)
+	if err != nil {
+		h.logs.Error(ctx, "failed to get post", zap.String("err", err.Error()))
+		return writeError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, post)
+}
+
+func (h *Handler) deletePost(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	p := &params{c: c}
+	postId := p.id("id", model.NodeTypePost)
+	if p.errors != nil {
+		return writeValidationError(c, p.errors)
+	}
+
+	h.logs.Debug(ctx, "Deleting post", zap.Int32("id", postId))
+
+	if err := h.service.DeletePost(ctx, postId); err != nil {
+		h.logs.Error(ctx, "failed to delete post", zap.String("err", err.Error()))
+		return writeError(c, err)
+	}
+
+	h.ps.Evict(ctx, postId)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// checkString validates a required string field against the @constraint directive of the matching GraphQL input field.
+func (h *Handler) checkString(p *params, input, field string, value *string) {
+	if value == nil {
+		p.invalid(field, "is required")
+		return
+	}
+
+	if message := h.validator.Check(input+"."+field, *value); message != "" {
+		p.invalid(field, message)
+	}
+}